@@ -0,0 +1,110 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/log"
+	"github.com/wyukawa/hadoop_exporter/collector"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+	"github.com/wyukawa/hadoop_exporter/rules"
+)
+
+var autoUpDesc = prometheus.NewDesc(
+	"hadoop_exporter_auto_up",
+	"Whether the roles to export could be detected from the JMX URL.",
+	nil, nil,
+)
+
+// autoDetector exports the roles served at a JMX URL. Detection is retried on
+// every scrape until it succeeds, so that the exporter can start before the
+// daemon it watches.
+type autoDetector struct {
+	url      string
+	ruleSets map[string]*rules.Set
+	client   *jmx.Client
+
+	mu        sync.Mutex
+	exporters []*collector.Exporter
+	// previous are the exporters of the detector this one replaced on a
+	// reload, whose scrape error counts are continued.
+	previous []*collector.Exporter
+}
+
+func newAutoDetector(url string, ruleSets map[string]*rules.Set, client *jmx.Client) *autoDetector {
+	a := &autoDetector{url: url, ruleSets: ruleSets, client: client}
+	a.detect()
+	return a
+}
+
+// detect returns the exporters of the detected roles, detecting them first
+// if they are not known yet. It returns nil if detection fails.
+func (a *autoDetector) detect() []*collector.Exporter {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.exporters != nil {
+		return a.exporters
+	}
+	exporters, err := detect(a.url, a.ruleSets, a.client)
+	if err != nil {
+		log.Errorf("Error detecting roles: %v", err)
+		return nil
+	}
+	for _, exporter := range exporters {
+		log.Infof("Detected %s", exporter.Role())
+		for _, p := range a.previous {
+			exporter.Inherit(p)
+		}
+	}
+	a.exporters = exporters
+	a.previous = nil
+	return exporters
+}
+
+// inherit makes the exporters detected by a continue the scrape error counts
+// of previous, the exporters of the detector it replaces.
+func (a *autoDetector) inherit(previous []*collector.Exporter) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.previous = previous
+	for _, e := range a.exporters {
+		for _, p := range previous {
+			e.Inherit(p)
+		}
+	}
+}
+
+// current returns the exporters of the roles detected so far.
+func (a *autoDetector) current() []*collector.Exporter {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.exporters
+}
+
+// Describe implements the prometheus.Collector interface. The detected
+// roles are not known in advance, so only hadoop_exporter_auto_up is described.
+func (a *autoDetector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- autoUpDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (a *autoDetector) Collect(ch chan<- prometheus.Metric) {
+	exporters := a.detect()
+	ch <- prometheus.MustNewConstMetric(autoUpDesc, prometheus.GaugeValue, boolToFloat(exporters != nil))
+	var wg sync.WaitGroup
+	for _, e := range exporters {
+		wg.Add(1)
+		go func(e *collector.Exporter) {
+			defer wg.Done()
+			e.Collect(ch)
+		}(e)
+	}
+	wg.Wait()
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
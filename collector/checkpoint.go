@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+// Checkpoint health, for catching a standby or secondary NameNode that
+// stopped checkpointing before the edit log grows too long to replay. The
+// transaction counts are exported by the rules from FSNamesystem.
+var (
+	checkpointAgeDesc = prometheus.NewDesc(
+		"namenode_seconds_since_last_checkpoint",
+		"Seconds since the last checkpoint, according to FSNamesystem's LastCheckpointTime.",
+		nil, nil,
+	)
+	lastAppliedTxIDDesc = prometheus.NewDesc(
+		"namenode_last_applied_or_written_txid",
+		"Last transaction written to the edit log, or applied from it on a standby.",
+		nil, nil,
+	)
+	checkpointTxIDDesc = prometheus.NewDesc(
+		"namenode_most_recent_checkpoint_txid",
+		"Transaction of the most recent checkpoint.",
+		nil, nil,
+	)
+)
+
+// journalTransactionInfo keys, from NameNodeInfo's JournalTransactionInfo.
+// Their values are strings.
+var journalTransactionInfo = map[string]*prometheus.Desc{
+	"LastAppliedOrWrittenTxId": lastAppliedTxIDDesc,
+	"MostRecentCheckpointTxId": checkpointTxIDDesc,
+}
+
+func collectCheckpoint(beans []jmx.Bean, ch chan<- prometheus.Metric) []error {
+	var errs []error
+	if bean, ok := findBean(beans, fsNamesystemBean); ok {
+		// LastCheckpointTime is in milliseconds.
+		if t, err := bean.Float("LastCheckpointTime"); err != nil {
+			errs = append(errs, err)
+		} else if t > 0 {
+			age := time.Since(time.Unix(0, int64(t)*int64(time.Millisecond))).Seconds()
+			gauge(ch, checkpointAgeDesc, age)
+		}
+	}
+	if bean, ok := findBean(beans, nameNodeInfoBean); ok {
+		var info map[string]string
+		if err := bean.JSON("JournalTransactionInfo", &info); err != nil {
+			return append(errs, err)
+		}
+		for key, desc := range journalTransactionInfo {
+			s, ok := info[key]
+			if !ok {
+				continue
+			}
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				errs = append(errs, &jmx.AttributeError{Bean: bean.Name, Attribute: "JournalTransactionInfo", Err: err})
+				continue
+			}
+			gauge(ch, desc, v)
+		}
+	}
+	return errs
+}
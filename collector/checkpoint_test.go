@@ -0,0 +1,117 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+func TestCollectCheckpoint(t *testing.T) {
+	for _, tc := range []struct {
+		fixture string
+		want    map[string]float64
+	}{
+		{
+			fixture: "namenode-2.7.json",
+			want: map[string]float64{
+				"namenode_last_applied_or_written_txid": 1523,
+				"namenode_most_recent_checkpoint_txid":  1500,
+			},
+		},
+		{
+			fixture: "namenode-3.3.json",
+			want: map[string]float64{
+				"namenode_last_applied_or_written_txid": 88,
+				"namenode_most_recent_checkpoint_txid":  80,
+			},
+		},
+		{
+			fixture: "namenode-3.4.json",
+			want: map[string]float64{
+				"namenode_last_applied_or_written_txid": 4200,
+				"namenode_most_recent_checkpoint_txid":  4000,
+			},
+		},
+	} {
+		t.Run(tc.fixture, func(t *testing.T) {
+			got, errs := scrapeFixture(t, scrapeFunc(collectCheckpoint), tc.fixture)
+			if len(errs) != 0 {
+				t.Errorf("errors: %v", errs)
+			}
+			checkMetrics(t, got, tc.want)
+		})
+	}
+}
+
+func TestCheckpointAge(t *testing.T) {
+	hourAgo := time.Now().Add(-time.Hour).UnixNano() / int64(time.Millisecond)
+	for _, tc := range []struct {
+		name  string
+		beans string
+		// age is the expected namenode_seconds_since_last_checkpoint, or 0
+		// if it is not exported.
+		age  float64
+		want map[string]float64
+		err  string
+	}{
+		{
+			name:  "checkpointed",
+			beans: fmt.Sprintf(`{"name":"Hadoop:service=NameNode,name=FSNamesystem","LastCheckpointTime":%d}`, hourAgo),
+			age:   3600,
+		},
+		{
+			// LastCheckpointTime is 0 until the first checkpoint.
+			name:  "never checkpointed",
+			beans: `{"name":"Hadoop:service=NameNode,name=FSNamesystem","LastCheckpointTime":0}`,
+		},
+		{
+			name:  "LastCheckpointTime missing",
+			beans: `{"name":"Hadoop:service=NameNode,name=FSNamesystem"}`,
+			err:   "LastCheckpointTime",
+		},
+		{
+			// The other transaction is still exported.
+			name:  "invalid transaction",
+			beans: `{"name":"Hadoop:service=NameNode,name=NameNodeInfo","JournalTransactionInfo":"{\"LastAppliedOrWrittenTxId\":\"x\",\"MostRecentCheckpointTxId\":\"80\"}"}`,
+			want:  map[string]float64{"namenode_most_recent_checkpoint_txid": 80},
+			err:   "JournalTransactionInfo",
+		},
+		{
+			name:  "invalid JournalTransactionInfo",
+			beans: `{"name":"Hadoop:service=NameNode,name=NameNodeInfo","JournalTransactionInfo":"{"}`,
+			err:   "JournalTransactionInfo",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var resp jmx.Response
+			if err := json.Unmarshal([]byte(`{"beans":[`+tc.beans+`]}`), &resp); err != nil {
+				t.Fatal(err)
+			}
+			var errs []error
+			got := gather(t, func(ch chan<- prometheus.Metric) { errs = collectCheckpoint(resp.Beans, ch) })
+			if tc.err == "" && len(errs) != 0 {
+				t.Errorf("errors: %v", errs)
+			}
+			if tc.err != "" && (len(errs) != 1 || !strings.Contains(errs[0].Error(), tc.err)) {
+				t.Errorf("errors = %v, want one about %s", errs, tc.err)
+			}
+			age, ok := got["namenode_seconds_since_last_checkpoint"]
+			delete(got, "namenode_seconds_since_last_checkpoint")
+			switch {
+			case tc.age == 0 && ok:
+				t.Errorf("unexpected namenode_seconds_since_last_checkpoint = %v", age)
+			case tc.age != 0 && (age < tc.age || age > tc.age+60):
+				t.Errorf("namenode_seconds_since_last_checkpoint = %v, want %v", age, tc.age)
+			}
+			if tc.want == nil {
+				tc.want = map[string]float64{}
+			}
+			checkMetrics(t, got, tc.want)
+		})
+	}
+}
@@ -0,0 +1,308 @@
+// Package collector implements a Prometheus collector for each Hadoop role.
+package collector
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/log"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+	"github.com/wyukawa/hadoop_exporter/rules"
+)
+
+// Roles lists the roles that have a collector.
+var Roles = []string{"namenode", "datanode", "journalnode", "resourcemanager", "nodemanager", "jobhistoryserver"}
+
+// RulesCollector is the name of the collector applying the bean-to-metric
+// rules, which every role has.
+const RulesCollector = "rules"
+
+// scraper produces metrics from the beans fetched from a daemon.
+type scraper interface {
+	Collect(beans []jmx.Bean, ch chan<- prometheus.Metric) []error
+}
+
+type role struct {
+	name string
+	// title names the daemon in help texts.
+	title string
+	// beans selects the beans the collectors are applied to from the /jmx
+	// document at the exporter's URL, for the roles that read nothing else.
+	beans func(resp *jmx.Response, opts Options) ([]jmx.Bean, error)
+	// fetch returns the beans the collectors are applied to, for the other
+	// roles.
+	fetch func(url string, opts Options) ([]jmx.Bean, error)
+	// collectors creates the role's collectors besides RulesCollector and
+	// JVMCollector, by name.
+	collectors map[string]func(opts Options) scraper
+}
+
+var roles = map[string]role{
+	"namenode":         {title: "NameNode JMX endpoint", beans: allBeans, collectors: namenodeCollectors},
+	"datanode":         {title: "DataNode JMX endpoint", beans: allBeans, collectors: datanodeCollectors},
+	"journalnode":      {title: "JournalNode JMX endpoint", beans: journalBeans},
+	"resourcemanager":  {title: "ResourceManager web endpoint", fetch: fetchResourceManager},
+	"nodemanager":      {title: "NodeManager JMX endpoint", beans: allBeans},
+	"jobhistoryserver": {title: "JobHistoryServer JMX endpoint", beans: allBeans},
+}
+
+// Options configures an Exporter.
+type Options struct {
+	// Rules maps beans to metrics. The role's built-in rules are used if it
+	// is nil.
+	Rules *rules.Set
+	// ClusterName selects the journal exported by a JournalNode collector.
+	// All journals are exported if it is empty.
+	ClusterName string
+	// Client fetches the daemon's documents. jmx.DefaultClient is used if
+	// it is nil.
+	Client *jmx.Client
+	// Labels are added to every metric.
+	Labels map[string]string
+	// Collectors lists the enabled collectors. All collectors of the role
+	// are enabled if it is empty.
+	Collectors []string
+	// TopUsers caps the users exported per operation and RPC server by the
+	// NameNode's top_users collector. DefaultTopUsers is used if it is not
+	// positive.
+	TopUsers int
+}
+
+// enabled reports whether the collector called name is enabled.
+func (o Options) enabled(name string) bool {
+	if len(o.Collectors) == 0 {
+		return true
+	}
+	for _, c := range o.Collectors {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchErrors is returned by a fetch function reading several documents when
+// some of them could not be fetched, or when some beans could not be decoded,
+// along with the beans it could read. The scrape only fails if there are none.
+type fetchErrors []error
+
+func (e fetchErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (o Options) client() *jmx.Client {
+	if o.Client == nil {
+		return jmx.DefaultClient
+	}
+	return o.Client
+}
+
+// Exporter collects the metrics of one Hadoop daemon. Its metrics are
+// prefixed with the name of the role.
+type Exporter struct {
+	role         role
+	url          string
+	opts         Options
+	scrapers     []scraper
+	labels       []*dto.LabelPair
+	up           *prometheus.Desc
+	scrapeErrors *prometheus.CounterVec
+	certExpiry   *prometheus.Desc
+}
+
+// New returns an Exporter for the daemon of the given role at url.
+func New(name, url string, opts Options) (*Exporter, error) {
+	r, ok := roles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown role %q", name)
+	}
+	r.name = name
+	if opts.Rules == nil {
+		var err error
+		if opts.Rules, err = rules.New(name, rules.Default(name)); err != nil {
+			return nil, err
+		}
+	}
+	e := &Exporter{
+		role:   r,
+		url:    url,
+		opts:   opts,
+		labels: labelPairs(opts.Labels),
+		// The labels distinguish the descriptors of exporters sharing a
+		// registry. up is created for every scrape so that concurrent
+		// scrapes each report their own result.
+		up: prometheus.NewDesc(
+			prometheus.BuildFQName(name, "", "up"),
+			"Whether the last scrape of the "+r.title+" succeeded.",
+			nil, opts.Labels,
+		),
+		scrapeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   name,
+			Name:        "scrape_errors_total",
+			Help:        "Number of errors while scraping the " + r.title + ", by reason.",
+			ConstLabels: opts.Labels,
+		}, []string{"reason"}),
+		certExpiry: prometheus.NewDesc(
+			prometheus.BuildFQName(name, "", "tls_cert_expiry_timestamp_seconds"),
+			"Earliest expiry of the certificates presented by the "+r.title+", as a Unix timestamp. Only exported for HTTPS endpoints.",
+			nil, nil,
+		),
+	}
+	collectors := opts.Collectors
+	if len(collectors) == 0 {
+		collectors = append(collectors, RulesCollector, JVMCollector)
+		for c := range r.collectors {
+			collectors = append(collectors, c)
+		}
+	}
+	for _, c := range collectors {
+		if c == RulesCollector {
+			e.scrapers = append(e.scrapers, opts.Rules)
+			continue
+		}
+		if c == JVMCollector {
+			e.scrapers = append(e.scrapers, newJVM(name))
+			continue
+		}
+		newScraper, ok := r.collectors[c]
+		if !ok {
+			return nil, fmt.Errorf("%s has no collector %q", name, c)
+		}
+		e.scrapers = append(e.scrapers, newScraper(opts))
+	}
+	return e, nil
+}
+
+// Role returns the name of the exporter's role.
+func (e *Exporter) Role() string {
+	return e.role.name
+}
+
+// URL returns the URL the exporter scrapes.
+func (e *Exporter) URL() string {
+	return e.url
+}
+
+// Client returns the client the exporter scrapes its URL with.
+func (e *Exporter) Client() *jmx.Client {
+	return e.opts.client()
+}
+
+// Inherit makes e continue the scrape error counts of prev, an exporter
+// created before the configuration was reloaded, so that
+// scrape_errors_total does not restart from zero on every reload. Nothing is
+// inherited unless prev scraped the same daemon with the same labels.
+func (e *Exporter) Inherit(prev *Exporter) {
+	if prev.role.name != e.role.name || prev.url != e.url || len(prev.opts.Labels) != len(e.opts.Labels) {
+		return
+	}
+	for k, v := range e.opts.Labels {
+		if pv, ok := prev.opts.Labels[k]; !ok || pv != v {
+			return
+		}
+	}
+	e.scrapeErrors = prev.scrapeErrors
+}
+
+// Describe implements the prometheus.Collector interface.
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- e.up
+	e.scrapeErrors.Describe(ch)
+}
+
+// Collect implements the prometheus.Collector interface.
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	e.Scrape(ch)
+}
+
+// Scrape collects the metrics like Collect and reports whether the endpoint
+// could be scraped.
+func (e *Exporter) Scrape(ch chan<- prometheus.Metric) bool {
+	return e.ScrapeDocument(nil, ch)
+}
+
+// ScrapeDocument scrapes like Scrape, reusing doc instead of fetching the
+// /jmx document at the exporter's URL again if it was fetched from there.
+// The documents of roles reading other ones are fetched anyway.
+func (e *Exporter) ScrapeDocument(doc *Document, ch chan<- prometheus.Metric) bool {
+	ch, done := withLabels(ch, e.labels)
+	defer done()
+	err := e.scrape(ch, doc)
+	if err != nil {
+		e.scrapeError(err)
+	}
+	ch <- prometheus.MustNewConstMetric(e.up, prometheus.GaugeValue, boolToFloat(err == nil))
+	e.scrapeErrors.Collect(ch)
+	return err == nil
+}
+
+// scrape sends every metric it could read to ch. It returns an error only
+// when the endpoint could not be fetched at all.
+func (e *Exporter) scrape(ch chan<- prometheus.Metric, doc *Document) error {
+	rec := &certRecorder{}
+	opts := e.opts
+	opts.Client = rec.client(e.opts.client())
+	var (
+		beans []jmx.Bean
+		err   error
+	)
+	switch {
+	case e.role.beans == nil:
+		beans, err = e.role.fetch(e.url, opts)
+	case doc != nil && doc.URL == e.url:
+		rec.record(doc.certExpiry)
+		beans, err = e.role.beans(doc.Response, opts)
+	default:
+		var resp *jmx.Response
+		if resp, err = opts.client().Fetch(e.url); err == nil {
+			beans, err = e.role.beans(resp, opts)
+		}
+	}
+	if expiry, ok := rec.earliestExpiry(); ok {
+		ch <- prometheus.MustNewConstMetric(e.certExpiry, prometheus.GaugeValue, float64(expiry.Unix()))
+	}
+	if _, ok := err.(fetchErrors); ok && len(beans) > 0 {
+		e.scrapeError(err)
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, s := range e.scrapers {
+		for _, err := range s.Collect(beans, ch) {
+			e.scrapeError(err)
+		}
+	}
+	return nil
+}
+
+func (e *Exporter) scrapeError(err error) {
+	if errs, ok := err.(fetchErrors); ok {
+		for _, err := range errs {
+			e.scrapeError(err)
+		}
+		return
+	}
+	log.Error(err)
+	e.scrapeErrors.WithLabelValues(jmx.Reason(err)).Inc()
+}
+
+// allBeans selects every bean of resp.
+func allBeans(resp *jmx.Response, opts Options) ([]jmx.Bean, error) {
+	return resp.Beans, skipped(resp)
+}
+
+// skipped returns the beans of resp that could not be decoded as a
+// fetchErrors, or nil.
+func skipped(resp *jmx.Response) error {
+	if len(resp.Skipped) == 0 {
+		return nil
+	}
+	return fetchErrors(resp.Skipped)
+}
@@ -0,0 +1,95 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestScrapeErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/partial/jmx":
+			// A bean without name.
+			fmt.Fprint(w, `{"beans":[{"modelerType":"x"},{"name":"Hadoop:service=NameNode,name=NameNodeInfo","Safemode":"Safe mode is ON. It was turned on manually."}]}`)
+		case "/attribute/jmx":
+			fmt.Fprint(w, `{"beans":[{"name":"Hadoop:service=NameNode,name=NameNodeInfo","Safemode":0}]}`)
+		case "/html/jmx":
+			fmt.Fprint(w, `<html></html>`)
+		default:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	for _, tc := range []struct {
+		name string
+		url  string
+		// want are the metrics after two scrapes.
+		want map[string]float64
+	}{
+		{
+			name: "down",
+			url:  down.URL + "/jmx",
+			want: map[string]float64{
+				`namenode_up{cluster="c"}`:                                   0,
+				`namenode_scrape_errors_total{cluster="c",reason="request"}`: 2,
+			},
+		},
+		{
+			name: "status",
+			url:  srv.URL + "/jmx",
+			want: map[string]float64{
+				`namenode_up{cluster="c"}`:                                  0,
+				`namenode_scrape_errors_total{cluster="c",reason="status"}`: 2,
+			},
+		},
+		{
+			name: "decode",
+			url:  srv.URL + "/html/jmx",
+			want: map[string]float64{
+				`namenode_up{cluster="c"}`:                                  0,
+				`namenode_scrape_errors_total{cluster="c",reason="decode"}`: 2,
+			},
+		},
+		{
+			// The beans that could be read are still collected.
+			name: "partial",
+			url:  srv.URL + "/partial/jmx",
+			want: map[string]float64{
+				`namenode_up{cluster="c"}`:                                  1,
+				`namenode_safemode_manual{cluster="c"}`:                     1,
+				`namenode_scrape_errors_total{cluster="c",reason="decode"}`: 2,
+			},
+		},
+		{
+			name: "attribute",
+			url:  srv.URL + "/attribute/jmx",
+			want: map[string]float64{
+				`namenode_up{cluster="c"}`: 1,
+				`namenode_scrape_errors_total{cluster="c",reason="invalid_attribute"}`: 2,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := New("namenode", tc.url, Options{
+				Collectors: []string{"safemode"},
+				Labels:     map[string]string{"cluster": "c"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ok bool
+			gather(t, func(ch chan<- prometheus.Metric) { ok = e.Scrape(ch) })
+			if ok != (tc.want[`namenode_up{cluster="c"}`] == 1) {
+				t.Errorf("Scrape() = %v", ok)
+			}
+			checkMetrics(t, gather(t, e.Collect), tc.want)
+		})
+	}
+}
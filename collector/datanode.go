@@ -0,0 +1,9 @@
+package collector
+
+// dataNodeInfoBean is read by the DataNode collectors.
+const dataNodeInfoBean = "Hadoop:service=DataNode,name=DataNodeInfo"
+
+// datanodeCollectors are the DataNode collectors besides RulesCollector.
+var datanodeCollectors = map[string]func(opts Options) scraper{
+	"outliers": func(Options) scraper { return scrapeFunc(collectDataNodeOutliers) },
+}
@@ -0,0 +1,227 @@
+package collector
+
+import (
+	"net"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+// The DataNodes known to a NameNode, from the LiveNodes, DeadNodes and
+// DecomNodes attributes of its NameNodeInfo bean, labelled by hostname.
+var (
+	datanodeLiveDesc = prometheus.NewDesc(
+		"namenode_datanode_live",
+		"Whether the DataNode is live (1) or dead (0).",
+		[]string{"hostname"}, nil,
+	)
+	datanodeLastContactDesc = prometheus.NewDesc(
+		"namenode_datanode_last_contact_seconds",
+		"Seconds since the last heartbeat of the DataNode.",
+		[]string{"hostname"}, nil,
+	)
+	datanodeCapacityDesc = prometheus.NewDesc(
+		"namenode_datanode_capacity_bytes",
+		"Configured capacity of the DataNode.",
+		[]string{"hostname"}, nil,
+	)
+	datanodeUsedDesc = prometheus.NewDesc(
+		"namenode_datanode_used_bytes",
+		"Space used by HDFS on the DataNode.",
+		[]string{"hostname"}, nil,
+	)
+	datanodeNonDFSUsedDesc = prometheus.NewDesc(
+		"namenode_datanode_non_dfs_used_bytes",
+		"Space used on the DataNode's volumes by other files than HDFS blocks.",
+		[]string{"hostname"}, nil,
+	)
+	datanodeRemainingDesc = prometheus.NewDesc(
+		"namenode_datanode_remaining_bytes",
+		"Space remaining for HDFS on the DataNode.",
+		[]string{"hostname"}, nil,
+	)
+	datanodeBlocksDesc = prometheus.NewDesc(
+		"namenode_datanode_blocks",
+		"Number of blocks stored on the DataNode.",
+		[]string{"hostname"}, nil,
+	)
+	datanodeFailedVolumesDesc = prometheus.NewDesc(
+		"namenode_datanode_failed_volumes",
+		"Number of failed volumes of the DataNode.",
+		[]string{"hostname"}, nil,
+	)
+	datanodeAdminStateDesc = prometheus.NewDesc(
+		"namenode_datanode_admin_state",
+		"Administrative state of the DataNode: 1 for the current state, 0 for the others.",
+		[]string{"hostname", "state"}, nil,
+	)
+	datanodeDecomUnderReplicatedDesc = prometheus.NewDesc(
+		"namenode_datanode_decommission_under_replicated_blocks",
+		"Number of blocks of the decommissioning DataNode that are not yet sufficiently replicated elsewhere.",
+		[]string{"hostname"}, nil,
+	)
+	datanodeDecomOnlyReplicasDesc = prometheus.NewDesc(
+		"namenode_datanode_decommission_only_replicas",
+		"Number of blocks whose only replicas are on decommissioning DataNodes, including this one.",
+		[]string{"hostname"}, nil,
+	)
+	datanodeDecomOpenFilesDesc = prometheus.NewDesc(
+		"namenode_datanode_decommission_under_replicated_in_open_files",
+		"Number of under-replicated blocks of the decommissioning DataNode that belong to files open for writing.",
+		[]string{"hostname"}, nil,
+	)
+	datanodeInfoDesc = prometheus.NewDesc(
+		"namenode_datanode_info",
+		"Addresses and version of the DataNode, always 1. The version is empty for dead DataNodes.",
+		[]string{"hostname", "xferaddr", "version"}, nil,
+	)
+)
+
+// adminStates maps the admin states reported by the NameNode to the values
+// of the state label.
+var adminStates = map[string]string{
+	"In Service":               "in_service",
+	"Decommission In Progress": "decommission_in_progress",
+	"Decommissioned":           "decommissioned",
+	"Entering Maintenance":     "entering_maintenance",
+	"In Maintenance":           "in_maintenance",
+}
+
+// liveNode is an entry of NameNodeInfo's LiveNodes.
+type liveNode struct {
+	XferAddr        string  `json:"xferaddr"`
+	LastContact     float64 `json:"lastContact"`
+	AdminState      string  `json:"adminState"`
+	Capacity        float64 `json:"capacity"`
+	UsedSpace       float64 `json:"usedSpace"`
+	NonDFSUsedSpace float64 `json:"nonDfsUsedSpace"`
+	Remaining       float64 `json:"remaining"`
+	NumBlocks       float64 `json:"numBlocks"`
+	VolFails        float64 `json:"volfails"`
+	Version         string  `json:"version"`
+}
+
+// deadNode is an entry of NameNodeInfo's DeadNodes. Hadoop 2 does not
+// report the admin state of dead nodes, only whether they are
+// decommissioned.
+type deadNode struct {
+	XferAddr       string  `json:"xferaddr"`
+	LastContact    float64 `json:"lastContact"`
+	AdminState     string  `json:"adminState"`
+	Decommissioned bool    `json:"decommissioned"`
+}
+
+// decomNode is an entry of NameNodeInfo's DecomNodes, the DataNodes being
+// decommissioned.
+type decomNode struct {
+	UnderReplicatedBlocks     float64 `json:"underReplicatedBlocks"`
+	DecommissionOnlyReplicas  float64 `json:"decommissionOnlyReplicas"`
+	UnderReplicateInOpenFiles float64 `json:"underReplicateInOpenFiles"`
+}
+
+func collectDataNodes(beans []jmx.Bean, ch chan<- prometheus.Metric) []error {
+	bean, ok := findBean(beans, nameNodeInfoBean)
+	if !ok {
+		return nil
+	}
+	var errs []error
+	// The nodes are keyed by host:xferPort.
+	var live map[string]liveNode
+	if err := bean.JSON("LiveNodes", &live); err != nil {
+		errs = append(errs, err)
+	}
+	var dead map[string]deadNode
+	if err := bean.JSON("DeadNodes", &dead); err != nil {
+		errs = append(errs, err)
+	}
+	var decom map[string]decomNode
+	if err := bean.JSON("DecomNodes", &decom); err != nil {
+		errs = append(errs, err)
+	}
+	var keys []string
+	for k := range live {
+		keys = append(keys, k)
+	}
+	for k := range dead {
+		if _, ok := live[k]; ok {
+			// The node was declared dead while LiveNodes was read.
+			delete(dead, k)
+			continue
+		}
+		keys = append(keys, k)
+	}
+	for k := range decom {
+		if _, ok := live[k]; !ok {
+			if _, ok := dead[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+	}
+	hostnames := datanodeHostnames(keys)
+
+	for k, n := range live {
+		host := hostnames[k]
+		gauge(ch, datanodeLiveDesc, 1, host)
+		gauge(ch, datanodeLastContactDesc, n.LastContact, host)
+		gauge(ch, datanodeCapacityDesc, n.Capacity, host)
+		gauge(ch, datanodeUsedDesc, n.UsedSpace, host)
+		gauge(ch, datanodeNonDFSUsedDesc, n.NonDFSUsedSpace, host)
+		gauge(ch, datanodeRemainingDesc, n.Remaining, host)
+		gauge(ch, datanodeBlocksDesc, n.NumBlocks, host)
+		gauge(ch, datanodeFailedVolumesDesc, n.VolFails, host)
+		collectAdminState(ch, host, n.AdminState)
+		gauge(ch, datanodeInfoDesc, 1, host, n.XferAddr, n.Version)
+	}
+	for k, n := range dead {
+		host := hostnames[k]
+		gauge(ch, datanodeLiveDesc, 0, host)
+		gauge(ch, datanodeLastContactDesc, n.LastContact, host)
+		state := n.AdminState
+		if state == "" && n.Decommissioned {
+			state = "Decommissioned"
+		}
+		collectAdminState(ch, host, state)
+		gauge(ch, datanodeInfoDesc, 1, host, n.XferAddr, "")
+	}
+	for k, n := range decom {
+		host := hostnames[k]
+		gauge(ch, datanodeDecomUnderReplicatedDesc, n.UnderReplicatedBlocks, host)
+		gauge(ch, datanodeDecomOnlyReplicasDesc, n.DecommissionOnlyReplicas, host)
+		gauge(ch, datanodeDecomOpenFilesDesc, n.UnderReplicateInOpenFiles, host)
+	}
+	return errs
+}
+
+// collectAdminState exports state, if known, as a set of 0/1 series.
+func collectAdminState(ch chan<- prometheus.Metric, host, state string) {
+	current, ok := adminStates[state]
+	if !ok {
+		return
+	}
+	for _, s := range adminStates {
+		gauge(ch, datanodeAdminStateDesc, boolToFloat(s == current), host, s)
+	}
+}
+
+// datanodeHostnames returns the hostname label of every node key. The port
+// is dropped, unless several DataNodes run on the same host, and is kept for
+// them so that their series stay distinct.
+func datanodeHostnames(keys []string) map[string]string {
+	hosts := map[string]string{}
+	count := map[string]int{}
+	for _, k := range keys {
+		host, _, err := net.SplitHostPort(k)
+		if err != nil {
+			// Hadoop releases before 2.8 key the nodes by hostname only.
+			host = k
+		}
+		hosts[k] = host
+		count[host]++
+	}
+	for k, host := range hosts {
+		if count[host] > 1 {
+			hosts[k] = k
+		}
+	}
+	return hosts
+}
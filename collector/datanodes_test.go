@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"fmt"
+	"testing"
+)
+
+// adminState returns the admin_state series of host in state.
+func adminState(host, state string) map[string]float64 {
+	m := map[string]float64{}
+	for _, s := range adminStates {
+		m[fmt.Sprintf(`namenode_datanode_admin_state{hostname=%q,state=%q}`, host, s)] = boolToFloat(s == state)
+	}
+	return m
+}
+
+func merge(maps ...map[string]float64) map[string]float64 {
+	out := map[string]float64{}
+	for _, m := range maps {
+		for k, v := range m {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func TestCollectDataNodes(t *testing.T) {
+	for _, tc := range []struct {
+		fixture string
+		want    map[string]float64
+	}{
+		{
+			// Nodes keyed by hostname, dead nodes without admin state.
+			fixture: "namenode-2.7.json",
+			want: merge(map[string]float64{
+				`namenode_datanode_live{hostname="dn1.example.com"}`:                                           1,
+				`namenode_datanode_live{hostname="dn2.example.com"}`:                                           1,
+				`namenode_datanode_live{hostname="dn3.example.com"}`:                                           0,
+				`namenode_datanode_last_contact_seconds{hostname="dn1.example.com"}`:                           1,
+				`namenode_datanode_last_contact_seconds{hostname="dn2.example.com"}`:                           2,
+				`namenode_datanode_last_contact_seconds{hostname="dn3.example.com"}`:                           4200,
+				`namenode_datanode_capacity_bytes{hostname="dn1.example.com"}`:                                 107374182400,
+				`namenode_datanode_capacity_bytes{hostname="dn2.example.com"}`:                                 107374182400,
+				`namenode_datanode_used_bytes{hostname="dn1.example.com"}`:                                     1073741824,
+				`namenode_datanode_used_bytes{hostname="dn2.example.com"}`:                                     2147483648,
+				`namenode_datanode_non_dfs_used_bytes{hostname="dn1.example.com"}`:                             536870912,
+				`namenode_datanode_non_dfs_used_bytes{hostname="dn2.example.com"}`:                             0,
+				`namenode_datanode_remaining_bytes{hostname="dn1.example.com"}`:                                105764257792,
+				`namenode_datanode_remaining_bytes{hostname="dn2.example.com"}`:                                105226698752,
+				`namenode_datanode_blocks{hostname="dn1.example.com"}`:                                         120,
+				`namenode_datanode_blocks{hostname="dn2.example.com"}`:                                         240,
+				`namenode_datanode_failed_volumes{hostname="dn1.example.com"}`:                                 0,
+				`namenode_datanode_failed_volumes{hostname="dn2.example.com"}`:                                 1,
+				`namenode_datanode_decommission_under_replicated_blocks{hostname="dn2.example.com"}`:           12,
+				`namenode_datanode_decommission_only_replicas{hostname="dn2.example.com"}`:                     3,
+				`namenode_datanode_decommission_under_replicated_in_open_files{hostname="dn2.example.com"}`:    1,
+				`namenode_datanode_info{hostname="dn1.example.com",version="2.7.3",xferaddr="10.0.0.1:50010"}`: 1,
+				`namenode_datanode_info{hostname="dn2.example.com",version="2.7.3",xferaddr="10.0.0.2:50010"}`: 1,
+				`namenode_datanode_info{hostname="dn3.example.com",version="",xferaddr="10.0.0.3:50010"}`:      1,
+			},
+				adminState("dn1.example.com", "in_service"),
+				adminState("dn2.example.com", "decommission_in_progress"),
+				adminState("dn3.example.com", "decommissioned"),
+			),
+		},
+		{
+			// Nodes keyed by host:port. The ports of the two DataNodes of
+			// dn2 are kept, and dn1, both live and dead, is live.
+			fixture: "namenode-3.3.json",
+			want: merge(map[string]float64{
+				`namenode_datanode_live{hostname="dn1.example.com"}`:                                               1,
+				`namenode_datanode_live{hostname="dn2.example.com:9866"}`:                                          1,
+				`namenode_datanode_live{hostname="dn2.example.com:9867"}`:                                          1,
+				`namenode_datanode_live{hostname="dn3.example.com"}`:                                               0,
+				`namenode_datanode_last_contact_seconds{hostname="dn1.example.com"}`:                               0,
+				`namenode_datanode_last_contact_seconds{hostname="dn2.example.com:9866"}`:                          1,
+				`namenode_datanode_last_contact_seconds{hostname="dn2.example.com:9867"}`:                          2,
+				`namenode_datanode_last_contact_seconds{hostname="dn3.example.com"}`:                               700,
+				`namenode_datanode_capacity_bytes{hostname="dn1.example.com"}`:                                     1000000,
+				`namenode_datanode_capacity_bytes{hostname="dn2.example.com:9866"}`:                                1000000,
+				`namenode_datanode_capacity_bytes{hostname="dn2.example.com:9867"}`:                                1000000,
+				`namenode_datanode_used_bytes{hostname="dn1.example.com"}`:                                         4096,
+				`namenode_datanode_used_bytes{hostname="dn2.example.com:9866"}`:                                    2048,
+				`namenode_datanode_used_bytes{hostname="dn2.example.com:9867"}`:                                    2048,
+				`namenode_datanode_non_dfs_used_bytes{hostname="dn1.example.com"}`:                                 1024,
+				`namenode_datanode_non_dfs_used_bytes{hostname="dn2.example.com:9866"}`:                            0,
+				`namenode_datanode_non_dfs_used_bytes{hostname="dn2.example.com:9867"}`:                            0,
+				`namenode_datanode_remaining_bytes{hostname="dn1.example.com"}`:                                    994880,
+				`namenode_datanode_remaining_bytes{hostname="dn2.example.com:9866"}`:                               997952,
+				`namenode_datanode_remaining_bytes{hostname="dn2.example.com:9867"}`:                               997952,
+				`namenode_datanode_blocks{hostname="dn1.example.com"}`:                                             10,
+				`namenode_datanode_blocks{hostname="dn2.example.com:9866"}`:                                        5,
+				`namenode_datanode_blocks{hostname="dn2.example.com:9867"}`:                                        5,
+				`namenode_datanode_failed_volumes{hostname="dn1.example.com"}`:                                     0,
+				`namenode_datanode_failed_volumes{hostname="dn2.example.com:9866"}`:                                0,
+				`namenode_datanode_failed_volumes{hostname="dn2.example.com:9867"}`:                                0,
+				`namenode_datanode_info{hostname="dn1.example.com",version="3.3.6",xferaddr="10.0.0.1:9866"}`:      1,
+				`namenode_datanode_info{hostname="dn2.example.com:9866",version="3.3.6",xferaddr="10.0.0.2:9866"}`: 1,
+				`namenode_datanode_info{hostname="dn2.example.com:9867",version="3.3.6",xferaddr="10.0.0.2:9867"}`: 1,
+				`namenode_datanode_info{hostname="dn3.example.com",version="",xferaddr="10.0.0.3:9866"}`:           1,
+			},
+				adminState("dn1.example.com", "in_service"),
+				adminState("dn2.example.com:9866", "in_maintenance"),
+				adminState("dn2.example.com:9867", "in_service"),
+				adminState("dn3.example.com", "in_service"),
+			),
+		},
+		{
+			fixture: "namenode-3.4.json",
+			want:    map[string]float64{},
+		},
+	} {
+		t.Run(tc.fixture, func(t *testing.T) {
+			got, errs := scrapeFixture(t, scrapeFunc(collectDataNodes), tc.fixture)
+			if len(errs) != 0 {
+				t.Errorf("errors: %v", errs)
+			}
+			checkMetrics(t, got, tc.want)
+		})
+	}
+}
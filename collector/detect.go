@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+// services maps the service in Hadoop:service=...,name=... ObjectNames to
+// roles.
+var services = map[string]string{
+	"NameNode":         "namenode",
+	"DataNode":         "datanode",
+	"JournalNode":      "journalnode",
+	"ResourceManager":  "resourcemanager",
+	"NodeManager":      "nodemanager",
+	"JobHistoryServer": "jobhistoryserver",
+}
+
+var serviceRE = regexp.MustCompile(`^Hadoop:service=([^,]+),`)
+
+// Target is a daemon to scrape with the collector of Role.
+type Target struct {
+	Role string
+	URL  string
+}
+
+// Document is a /jmx document, fetched once to detect the roles served at
+// its URL and scraped by their exporters with Exporter.ScrapeDocument.
+type Document struct {
+	URL      string
+	Response *jmx.Response
+	// certExpiry is the earliest expiry of the certificates presented
+	// when it was fetched.
+	certExpiry time.Time
+}
+
+// FetchDocument fetches the /jmx document at url. client is
+// jmx.DefaultClient if nil.
+func FetchDocument(url string, client *jmx.Client) (*Document, error) {
+	if client == nil {
+		client = jmx.DefaultClient
+	}
+	rec := &certRecorder{}
+	resp, err := rec.client(client).Fetch(url)
+	if err != nil {
+		return nil, err
+	}
+	expiry, _ := rec.earliestExpiry()
+	return &Document{URL: url, Response: resp, certExpiry: expiry}, nil
+}
+
+// Targets returns a target for every role whose beans the document
+// contains, in the order of Roles.
+func (d *Document) Targets() []Target {
+	found := map[string]bool{}
+	for _, bean := range d.Response.Beans {
+		if m := serviceRE.FindStringSubmatch(bean.Name); m != nil {
+			if role, ok := services[m[1]]; ok {
+				found[role] = true
+			}
+		}
+	}
+	var targets []Target
+	for _, role := range Roles {
+		if !found[role] {
+			continue
+		}
+		t := Target{Role: role, URL: d.URL}
+		if role == "resourcemanager" {
+			// The ResourceManager collector reads /jmx and the REST API
+			// under the web address.
+			t.URL = strings.TrimSuffix(strings.TrimSuffix(d.URL, "/"), "/jmx")
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+// Detect fetches the /jmx document at url once and returns a target for
+// every role whose beans it contains, in the order of Roles. client is
+// jmx.DefaultClient if nil.
+func Detect(url string, client *jmx.Client) ([]Target, error) {
+	doc, err := FetchDocument(url, client)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Targets(), nil
+}
@@ -0,0 +1,64 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/namenode/jmx":
+			fmt.Fprint(w, `{"beans":[
+				{"name":"Hadoop:service=NameNode,name=NameNodeInfo"},
+				{"name":"java.lang:type=Memory"}
+			]}`)
+		case "/yarn/jmx":
+			// A ResourceManager running in the same JVM as a NodeManager.
+			fmt.Fprint(w, `{"beans":[
+				{"name":"Hadoop:service=NodeManager,name=NodeManagerMetrics"},
+				{"name":"Hadoop:service=ResourceManager,name=RMNMInfo"},
+				{"name":"Hadoop:service=Unknown,name=Other"}
+			]}`)
+		case "/other/jmx":
+			fmt.Fprint(w, `{"beans":[{"name":"java.lang:type=Memory"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		path string
+		want []Target
+		err  bool
+	}{
+		{
+			path: "/namenode/jmx",
+			want: []Target{{Role: "namenode", URL: srv.URL + "/namenode/jmx"}},
+		},
+		{
+			// The ResourceManager is scraped from its web address.
+			path: "/yarn/jmx",
+			want: []Target{
+				{Role: "resourcemanager", URL: srv.URL + "/yarn"},
+				{Role: "nodemanager", URL: srv.URL + "/yarn/jmx"},
+			},
+		},
+		{path: "/other/jmx"},
+		{path: "/missing/jmx", err: true},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			got, err := Detect(srv.URL+tc.path, nil)
+			if (err != nil) != tc.err {
+				t.Fatalf("Detect() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Detect() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
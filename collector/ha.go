@@ -0,0 +1,148 @@
+package collector
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/log"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+const nameNodeStatusBean = "Hadoop:service=NameNode,name=NameNodeStatus"
+
+// haStates are the HA service states a NameNode reports in NameNodeStatus's
+// State, observer being Hadoop 3.2's read-only standby.
+var haStates = []string{"initializing", "active", "standby", "observer", "stopping"}
+
+var (
+	haUpDesc = prometheus.NewDesc(
+		"namenode_ha_up",
+		"Whether the NameNode's HA state could be read.",
+		[]string{"nn"}, nil,
+	)
+	haStateDesc = prometheus.NewDesc(
+		"namenode_ha_state",
+		"HA state of the NameNode: 1 for the current state, 0 for the others.",
+		[]string{"nn", "state"}, nil,
+	)
+	haLastTransitionDesc = prometheus.NewDesc(
+		"namenode_ha_last_transition_timestamp_seconds",
+		"Time of the NameNode's last HA state transition, as a Unix timestamp. Not exported before the first transition.",
+		[]string{"nn"}, nil,
+	)
+	haActiveCountDesc = prometheus.NewDesc(
+		"namenode_ha_active_count",
+		"Number of active NameNodes in the nameservice. Anything but 1 needs attention.",
+		nil, nil,
+	)
+)
+
+// HANameNode is a NameNode of a nameservice.
+type HANameNode struct {
+	// Name is the NameNode ID, such as nn1, exported as the nn label.
+	Name string
+	// URL is the NameNode's /jmx URL.
+	URL string
+	// Client fetches the URL. jmx.DefaultClient is used if it is nil.
+	Client *jmx.Client
+}
+
+// HA collects the HA state of every NameNode of a nameservice, so that
+// failovers and the number of active NameNodes can be watched from one
+// place. Only the NameNodeStatus bean is fetched from each NameNode.
+type HA struct {
+	namenodes []HANameNode
+	labels    []*dto.LabelPair
+}
+
+// NewHA returns a collector for the NameNodes of one nameservice. labels are
+// added to every metric.
+func NewHA(namenodes []HANameNode, labels map[string]string) *HA {
+	return &HA{namenodes: namenodes, labels: labelPairs(labels)}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (h *HA) Describe(ch chan<- *prometheus.Desc) {
+	ch <- haUpDesc
+	ch <- haStateDesc
+	ch <- haLastTransitionDesc
+	ch <- haActiveCountDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (h *HA) Collect(ch chan<- prometheus.Metric) {
+	ch, done := withLabels(ch, h.labels)
+	defer done()
+
+	states := make([]string, len(h.namenodes))
+	var wg sync.WaitGroup
+	for i, nn := range h.namenodes {
+		wg.Add(1)
+		go func(i int, nn HANameNode) {
+			defer wg.Done()
+			states[i] = h.collectNameNode(nn, ch)
+		}(i, nn)
+	}
+	wg.Wait()
+
+	active := 0
+	for _, s := range states {
+		if s == "active" {
+			active++
+		}
+	}
+	gauge(ch, haActiveCountDesc, float64(active))
+}
+
+// collectNameNode exports the state of nn and returns it, or "" if it could
+// not be read.
+func (h *HA) collectNameNode(nn HANameNode, ch chan<- prometheus.Metric) string {
+	bean, err := fetchNameNodeStatus(nn)
+	var state string
+	if err == nil {
+		state, err = bean.String("State")
+	}
+	if err != nil {
+		log.Errorf("HA state of NameNode %s: %v", nn.Name, err)
+		gauge(ch, haUpDesc, 0, nn.Name)
+		return ""
+	}
+	gauge(ch, haUpDesc, 1, nn.Name)
+	state = strings.ToLower(state)
+	for _, s := range haStates {
+		gauge(ch, haStateDesc, boolToFloat(s == state), nn.Name, s)
+	}
+	// LastHATransitionTime is in milliseconds, and 0 until the first
+	// transition. Releases before 2.8 do not report it.
+	if t, err := bean.Float("LastHATransitionTime"); err == nil && t > 0 {
+		gauge(ch, haLastTransitionDesc, t/1000, nn.Name)
+	}
+	return state
+}
+
+// fetchNameNodeStatus fetches only the NameNodeStatus bean, rather than the
+// whole /jmx document, whose NameNodeInfo bean lists every DataNode.
+func fetchNameNodeStatus(nn HANameNode) (jmx.Bean, error) {
+	client := nn.Client
+	if client == nil {
+		client = jmx.DefaultClient
+	}
+	u := nn.URL
+	if strings.Contains(u, "?") {
+		u += "&"
+	} else {
+		u += "?"
+	}
+	resp, err := client.Fetch(u + "qry=" + url.QueryEscape(nameNodeStatusBean))
+	if err != nil {
+		return jmx.Bean{}, err
+	}
+	bean, ok := resp.Bean(nameNodeStatusBean)
+	if !ok {
+		return jmx.Bean{}, &jmx.AttributeError{Bean: nameNodeStatusBean, Attribute: "State", Err: jmx.ErrMissing}
+	}
+	return bean, nil
+}
@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHA(t *testing.T) {
+	// Each NameNode replays its NameNodeStatus beans, one per scrape. An
+	// empty bean stands for a NameNode that is down.
+	nn1 := []string{
+		`{"name":"Hadoop:service=NameNode,name=NameNodeStatus","State":"active","LastHATransitionTime":0}`,
+		`{"name":"Hadoop:service=NameNode,name=NameNodeStatus","State":"standby","LastHATransitionTime":1700000100000}`,
+		``,
+	}
+	nn2 := []string{
+		`{"name":"Hadoop:service=NameNode,name=NameNodeStatus","State":"standby","LastHATransitionTime":0}`,
+		`{"name":"Hadoop:service=NameNode,name=NameNodeStatus","State":"active","LastHATransitionTime":1700000101500}`,
+		`{"name":"Hadoop:service=NameNode,name=NameNodeStatus","State":"active","LastHATransitionTime":1700000101500}`,
+	}
+	step := 0
+	replay := func(beans []string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if qry := r.URL.Query().Get("qry"); qry != nameNodeStatusBean {
+				t.Errorf("qry = %q, want %q", qry, nameNodeStatusBean)
+			}
+			if beans[step] == "" {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprintf(w, `{"beans":[%s]}`, beans[step])
+		}))
+	}
+	srv1, srv2 := replay(nn1), replay(nn2)
+	defer srv1.Close()
+	defer srv2.Close()
+	ha := NewHA([]HANameNode{{Name: "nn1", URL: srv1.URL + "/jmx"}, {Name: "nn2", URL: srv2.URL + "/jmx"}}, map[string]string{"nameservice": "ns1"})
+
+	// states returns the namenode_ha_state series of nn, in state.
+	states := func(nn, state string) map[string]float64 {
+		m := map[string]float64{}
+		for _, s := range haStates {
+			m[fmt.Sprintf(`namenode_ha_state{nameservice="ns1",nn=%q,state=%q}`, nn, s)] = boolToFloat(s == state)
+		}
+		return m
+	}
+	for i, want := range []map[string]float64{
+		// No transition yet.
+		merge(states("nn1", "active"), states("nn2", "standby"), map[string]float64{
+			`namenode_ha_up{nameservice="ns1",nn="nn1"}`:  1,
+			`namenode_ha_up{nameservice="ns1",nn="nn2"}`:  1,
+			`namenode_ha_active_count{nameservice="ns1"}`: 1,
+		}),
+		// Failover to nn2.
+		merge(states("nn1", "standby"), states("nn2", "active"), map[string]float64{
+			`namenode_ha_up{nameservice="ns1",nn="nn1"}`:                                1,
+			`namenode_ha_up{nameservice="ns1",nn="nn2"}`:                                1,
+			`namenode_ha_last_transition_timestamp_seconds{nameservice="ns1",nn="nn1"}`: 1700000100,
+			`namenode_ha_last_transition_timestamp_seconds{nameservice="ns1",nn="nn2"}`: 1700000101.5,
+			`namenode_ha_active_count{nameservice="ns1"}`:                               1,
+		}),
+		// nn1 is down.
+		merge(states("nn2", "active"), map[string]float64{
+			`namenode_ha_up{nameservice="ns1",nn="nn1"}`:                                0,
+			`namenode_ha_up{nameservice="ns1",nn="nn2"}`:                                1,
+			`namenode_ha_last_transition_timestamp_seconds{nameservice="ns1",nn="nn2"}`: 1700000101.5,
+			`namenode_ha_active_count{nameservice="ns1"}`:                               1,
+		}),
+	} {
+		step = i
+		t.Run(fmt.Sprintf("scrape %d", i), func(t *testing.T) {
+			checkMetrics(t, gather(t, ha.Collect), want)
+		})
+	}
+}
+
+func TestHAActiveCount(t *testing.T) {
+	// Both NameNodes claim to be active, as after a failed fencing.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"beans":[{"name":"Hadoop:service=NameNode,name=NameNodeStatus","State":"active"}]}`)
+	}))
+	defer srv.Close()
+	ha := NewHA([]HANameNode{{Name: "nn1", URL: srv.URL + "/jmx"}, {Name: "nn2", URL: srv.URL + "/jmx?a=b"}}, nil)
+	if got := gather(t, ha.Collect)["namenode_ha_active_count"]; got != 2 {
+		t.Errorf("namenode_ha_active_count = %v, want 2", got)
+	}
+}
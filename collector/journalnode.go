@@ -0,0 +1,25 @@
+package collector
+
+import (
+	"strings"
+
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+const journalBeanPrefix = "Hadoop:service=JournalNode,name=Journal-"
+
+// journalBeans selects the JournalNode's beans, dropping the journals of
+// other clusters than opts.ClusterName.
+func journalBeans(resp *jmx.Response, opts Options) ([]jmx.Bean, error) {
+	if opts.ClusterName == "" {
+		return resp.Beans, skipped(resp)
+	}
+	var beans []jmx.Bean
+	for _, bean := range resp.Beans {
+		if strings.HasPrefix(bean.Name, journalBeanPrefix) && bean.Name != journalBeanPrefix+opts.ClusterName {
+			continue
+		}
+		beans = append(beans, bean)
+	}
+	return beans, skipped(resp)
+}
@@ -0,0 +1,209 @@
+package collector
+
+import (
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+// JVMCollector is the name of the collector exporting the JVM's platform
+// beans and Hadoop's JvmMetrics, which every role has.
+const JVMCollector = "jvm"
+
+// Beans read by the JVM collector.
+const (
+	memoryBean          = "java.lang:type=Memory"
+	operatingSystemBean = "java.lang:type=OperatingSystem"
+	classLoadingBean    = "java.lang:type=ClassLoading"
+	runtimeBean         = "java.lang:type=Runtime"
+)
+
+var (
+	memoryPoolRE       = regexp.MustCompile(`^java\.lang:type=MemoryPool,name=(.+)$`)
+	garbageCollectorRE = regexp.MustCompile(`^java\.lang:type=GarbageCollector,name=(.+)$`)
+	jvmMetricsRE       = regexp.MustCompile(`^Hadoop:service=[^,]+,name=JvmMetrics$`)
+)
+
+// memoryUsageFields are the fields of a java.lang.management.MemoryUsage.
+var memoryUsageFields = []string{"used", "committed", "max", "init"}
+
+// threadStates are the thread states counted by JvmMetrics' Threads*
+// attributes, by attribute.
+var threadStates = map[string]string{
+	"ThreadsNew":          "new",
+	"ThreadsRunnable":     "runnable",
+	"ThreadsBlocked":      "blocked",
+	"ThreadsWaiting":      "waiting",
+	"ThreadsTimedWaiting": "timed_waiting",
+	"ThreadsTerminated":   "terminated",
+}
+
+// logLevels are the levels counted by JvmMetrics' Log* attributes.
+var logLevels = map[string]string{
+	"LogFatal": "fatal",
+	"LogError": "error",
+	"LogWarn":  "warn",
+	"LogInfo":  "info",
+}
+
+// jvm exports the memory areas and pools, garbage collectors, threads, file
+// descriptors, class loading and uptime of the daemon's JVM. JvmMetrics'
+// GcCount and GcTimeMillis are the sums of the GarbageCollector beans, which
+// are exported instead.
+type jvm struct {
+	memory           *prometheus.Desc
+	memoryPool       *prometheus.Desc
+	gcCollections    *prometheus.Desc
+	gcSeconds        *prometheus.Desc
+	gcExtraSleep     *prometheus.Desc
+	gcWarnThreshold  *prometheus.Desc
+	gcInfoThreshold  *prometheus.Desc
+	threads          *prometheus.Desc
+	logEvents        *prometheus.Desc
+	openFDs          *prometheus.Desc
+	maxFDs           *prometheus.Desc
+	classesLoaded    *prometheus.Desc
+	classesLoadedAll *prometheus.Desc
+	classesUnloaded  *prometheus.Desc
+	startTime        *prometheus.Desc
+	uptime           *prometheus.Desc
+}
+
+// newJVM returns the JVM collector of role, whose metrics are prefixed with
+// role_jvm_.
+func newJVM(role string) scraper {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(role, "jvm", name), help, labels, nil)
+	}
+	return &jvm{
+		memory:           desc("memory_bytes", "Heap or non-heap memory of the JVM, by area and by field of the MemoryUsage: used, committed, max or init. max is not exported when undefined.", "area", "field"),
+		memoryPool:       desc("memory_pool_bytes", "Memory of the JVM's memory pool, by field of the MemoryUsage: used, committed, max or init. max is not exported when undefined.", "pool", "field"),
+		gcCollections:    desc("gc_collections_total", "Number of collections of the garbage collector.", "gc"),
+		gcSeconds:        desc("gc_collection_seconds_total", "Time spent in collections of the garbage collector.", "gc"),
+		gcExtraSleep:     desc("gc_extra_sleep_seconds_total", "Time the JvmPauseMonitor slept more than it asked to, a measure of GC pauses."),
+		gcWarnThreshold:  desc("gc_pause_warn_threshold_exceeded_total", "Number of pauses longer than jvm.pause.warn-threshold.ms."),
+		gcInfoThreshold:  desc("gc_pause_info_threshold_exceeded_total", "Number of pauses longer than jvm.pause.info-threshold.ms."),
+		threads:          desc("threads", "Number of the daemon's threads, by state.", "state"),
+		logEvents:        desc("log_events_total", "Number of events logged by the daemon, by level.", "level"),
+		openFDs:          desc("open_fds", "Number of open file descriptors."),
+		maxFDs:           desc("max_fds", "Maximum number of open file descriptors."),
+		classesLoaded:    desc("classes_loaded", "Number of classes currently loaded."),
+		classesLoadedAll: desc("classes_loaded_total", "Number of classes loaded since the JVM started."),
+		classesUnloaded:  desc("classes_unloaded_total", "Number of classes unloaded since the JVM started."),
+		startTime:        desc("start_time_seconds", "Start time of the JVM, as a Unix timestamp."),
+		uptime:           desc("uptime_seconds", "Time since the JVM started."),
+	}
+}
+
+func (j *jvm) Collect(beans []jmx.Bean, ch chan<- prometheus.Metric) []error {
+	var errs []error
+	for _, bean := range beans {
+		var err error
+		switch {
+		case bean.Name == memoryBean:
+			err = j.collectMemory(bean, ch)
+		case bean.Name == operatingSystemBean:
+			// OperatingSystem only has file descriptors on Unix.
+			j.collect(bean, ch, prometheus.GaugeValue, map[string]*prometheus.Desc{
+				"OpenFileDescriptorCount": j.openFDs,
+				"MaxFileDescriptorCount":  j.maxFDs,
+			}, 1)
+		case bean.Name == classLoadingBean:
+			j.collect(bean, ch, prometheus.GaugeValue, map[string]*prometheus.Desc{
+				"LoadedClassCount": j.classesLoaded,
+			}, 1)
+			j.collect(bean, ch, prometheus.CounterValue, map[string]*prometheus.Desc{
+				"TotalLoadedClassCount": j.classesLoadedAll,
+				"UnloadedClassCount":    j.classesUnloaded,
+			}, 1)
+		case bean.Name == runtimeBean:
+			// StartTime and Uptime are in milliseconds.
+			j.collect(bean, ch, prometheus.GaugeValue, map[string]*prometheus.Desc{
+				"StartTime": j.startTime,
+				"Uptime":    j.uptime,
+			}, 1000)
+		case jvmMetricsRE.MatchString(bean.Name):
+			j.collectJvmMetrics(bean, ch)
+		default:
+			if m := memoryPoolRE.FindStringSubmatch(bean.Name); m != nil {
+				err = j.collectMemoryUsage(bean, "Usage", j.memoryPool, m[1], ch)
+			} else if m := garbageCollectorRE.FindStringSubmatch(bean.Name); m != nil {
+				j.collectGarbageCollector(bean, m[1], ch)
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// collect exports the attributes of bean found in descs, divided by scale.
+func (j *jvm) collect(bean jmx.Bean, ch chan<- prometheus.Metric, t prometheus.ValueType, descs map[string]*prometheus.Desc, scale float64) {
+	for attr, desc := range descs {
+		if v, err := bean.Float(attr); err == nil {
+			ch <- prometheus.MustNewConstMetric(desc, t, v/scale)
+		}
+	}
+}
+
+func (j *jvm) collectMemory(bean jmx.Bean, ch chan<- prometheus.Metric) error {
+	if err := j.collectMemoryUsage(bean, "HeapMemoryUsage", j.memory, "heap", ch); err != nil {
+		return err
+	}
+	return j.collectMemoryUsage(bean, "NonHeapMemoryUsage", j.memory, "nonheap", ch)
+}
+
+// collectMemoryUsage exports the MemoryUsage in attr with label as the first
+// label value.
+func (j *jvm) collectMemoryUsage(bean jmx.Bean, attr string, desc *prometheus.Desc, label string, ch chan<- prometheus.Metric) error {
+	usage, err := bean.Composite(attr)
+	if err != nil {
+		return err
+	}
+	for _, field := range memoryUsageFields {
+		v, err := usage.Float(field)
+		if err != nil {
+			return err
+		}
+		if v < 0 {
+			// max and init are -1 when undefined.
+			continue
+		}
+		gauge(ch, desc, v, label, field)
+	}
+	return nil
+}
+
+func (j *jvm) collectGarbageCollector(bean jmx.Bean, gc string, ch chan<- prometheus.Metric) {
+	if v, err := bean.Float("CollectionCount"); err == nil && v >= 0 {
+		ch <- prometheus.MustNewConstMetric(j.gcCollections, prometheus.CounterValue, v, gc)
+	}
+	// CollectionTime is in milliseconds.
+	if v, err := bean.Float("CollectionTime"); err == nil && v >= 0 {
+		ch <- prometheus.MustNewConstMetric(j.gcSeconds, prometheus.CounterValue, v/1000, gc)
+	}
+}
+
+func (j *jvm) collectJvmMetrics(bean jmx.Bean, ch chan<- prometheus.Metric) {
+	// GcTotalExtraSleepTime is in milliseconds. The JvmPauseMonitor
+	// attributes are missing when it is not running.
+	j.collect(bean, ch, prometheus.CounterValue, map[string]*prometheus.Desc{
+		"GcTotalExtraSleepTime": j.gcExtraSleep,
+	}, 1000)
+	j.collect(bean, ch, prometheus.CounterValue, map[string]*prometheus.Desc{
+		"GcNumWarnThresholdExceeded": j.gcWarnThreshold,
+		"GcNumInfoThresholdExceeded": j.gcInfoThreshold,
+	}, 1)
+	for attr, state := range threadStates {
+		if v, err := bean.Float(attr); err == nil {
+			gauge(ch, j.threads, v, state)
+		}
+	}
+	for attr, level := range logLevels {
+		if v, err := bean.Float(attr); err == nil {
+			ch <- prometheus.MustNewConstMetric(j.logEvents, prometheus.CounterValue, v, level)
+		}
+	}
+}
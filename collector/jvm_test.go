@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+func TestJVM(t *testing.T) {
+	got, errs := scrapeFixture(t, newJVM("datanode"), "jvm.json")
+	if len(errs) != 0 {
+		t.Errorf("errors: %v", errs)
+	}
+	checkMetrics(t, got, map[string]float64{
+		// max is not exported when undefined.
+		`datanode_jvm_memory_bytes{area="heap",field="used"}`:                 536870912,
+		`datanode_jvm_memory_bytes{area="heap",field="committed"}`:            1073741824,
+		`datanode_jvm_memory_bytes{area="heap",field="max"}`:                  4294967296,
+		`datanode_jvm_memory_bytes{area="heap",field="init"}`:                 1073741824,
+		`datanode_jvm_memory_bytes{area="nonheap",field="used"}`:              95420416,
+		`datanode_jvm_memory_bytes{area="nonheap",field="committed"}`:         100663296,
+		`datanode_jvm_memory_bytes{area="nonheap",field="init"}`:              2555904,
+		`datanode_jvm_memory_pool_bytes{field="used",pool="G1 Old Gen"}`:      402653184,
+		`datanode_jvm_memory_pool_bytes{field="committed",pool="G1 Old Gen"}`: 805306368,
+		`datanode_jvm_memory_pool_bytes{field="max",pool="G1 Old Gen"}`:       4294967296,
+		`datanode_jvm_memory_pool_bytes{field="init",pool="G1 Old Gen"}`:      1006632960,
+		`datanode_jvm_memory_pool_bytes{field="used",pool="Metaspace"}`:       60817408,
+		`datanode_jvm_memory_pool_bytes{field="committed",pool="Metaspace"}`:  62914560,
+		`datanode_jvm_memory_pool_bytes{field="init",pool="Metaspace"}`:       0,
+		`datanode_jvm_gc_collections_total{gc="G1 Young Generation"}`:         16,
+		`datanode_jvm_gc_collections_total{gc="G1 Old Generation"}`:           1,
+		`datanode_jvm_gc_collection_seconds_total{gc="G1 Young Generation"}`:  0.85,
+		`datanode_jvm_gc_collection_seconds_total{gc="G1 Old Generation"}`:    0.1,
+		"datanode_jvm_gc_extra_sleep_seconds_total":                           12.5,
+		"datanode_jvm_gc_pause_warn_threshold_exceeded_total":                 1,
+		"datanode_jvm_gc_pause_info_threshold_exceeded_total":                 4,
+		`datanode_jvm_threads{state="new"}`:                                   0,
+		`datanode_jvm_threads{state="runnable"}`:                              21,
+		`datanode_jvm_threads{state="blocked"}`:                               2,
+		`datanode_jvm_threads{state="waiting"}`:                               30,
+		`datanode_jvm_threads{state="timed_waiting"}`:                         45,
+		`datanode_jvm_threads{state="terminated"}`:                            0,
+		`datanode_jvm_log_events_total{level="fatal"}`:                        0,
+		`datanode_jvm_log_events_total{level="error"}`:                        3,
+		`datanode_jvm_log_events_total{level="warn"}`:                         120,
+		`datanode_jvm_log_events_total{level="info"}`:                         5000,
+		"datanode_jvm_open_fds":                                               312,
+		"datanode_jvm_max_fds":                                                65536,
+		"datanode_jvm_classes_loaded":                                         9000,
+		"datanode_jvm_classes_loaded_total":                                   9100,
+		"datanode_jvm_classes_unloaded_total":                                 100,
+		"datanode_jvm_start_time_seconds":                                     1714640400,
+		"datanode_jvm_uptime_seconds":                                         86400.5,
+	})
+}
+
+func TestJVMErrors(t *testing.T) {
+	// A MemoryUsage that is not a composite fails the memory bean only.
+	var resp jmx.Response
+	if err := json.Unmarshal([]byte(`{"beans":[
+		{"name":"java.lang:type=Memory","HeapMemoryUsage":"unavailable"},
+		{"name":"java.lang:type=ClassLoading","LoadedClassCount":9000}
+	]}`), &resp); err != nil {
+		t.Fatal(err)
+	}
+	var errs []error
+	got := gather(t, func(ch chan<- prometheus.Metric) { errs = newJVM("namenode").Collect(resp.Beans, ch) })
+	if len(errs) != 1 {
+		t.Errorf("errors = %v, want one", errs)
+	}
+	checkMetrics(t, got, map[string]float64{"namenode_jvm_classes_loaded": 9000})
+}
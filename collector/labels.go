@@ -0,0 +1,62 @@
+package collector
+
+import (
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// labeledMetric adds constant labels to a metric. Labels the metric already
+// has are kept.
+type labeledMetric struct {
+	prometheus.Metric
+	labels []*dto.LabelPair
+}
+
+// Write implements the prometheus.Metric interface.
+func (m labeledMetric) Write(out *dto.Metric) error {
+	if err := m.Metric.Write(out); err != nil {
+		return err
+	}
+	have := map[string]bool{}
+	for _, l := range out.Label {
+		have[l.GetName()] = true
+	}
+	for _, l := range m.labels {
+		if !have[l.GetName()] {
+			out.Label = append(out.Label, l)
+		}
+	}
+	sort.Sort(prometheus.LabelPairSorter(out.Label))
+	return nil
+}
+
+func labelPairs(labels map[string]string) []*dto.LabelPair {
+	var pairs []*dto.LabelPair
+	for name, value := range labels {
+		pairs = append(pairs, &dto.LabelPair{Name: proto.String(name), Value: proto.String(value)})
+	}
+	return pairs
+}
+
+// withLabels returns a channel whose metrics are forwarded to ch with labels
+// added, and a function that must be called once nothing more is sent on it.
+func withLabels(ch chan<- prometheus.Metric, labels []*dto.LabelPair) (chan<- prometheus.Metric, func()) {
+	if len(labels) == 0 {
+		return ch, func() {}
+	}
+	in := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		for m := range in {
+			ch <- labeledMetric{Metric: m, labels: labels}
+		}
+		close(done)
+	}()
+	return in, func() {
+		close(in)
+		<-done
+	}
+}
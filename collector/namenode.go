@@ -0,0 +1,48 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+// Beans read by the NameNode collectors.
+const (
+	fsNamesystemBean      = "Hadoop:service=NameNode,name=FSNamesystem"
+	nameNodeInfoBean      = "Hadoop:service=NameNode,name=NameNodeInfo"
+	fsNamesystemStateBean = "Hadoop:service=NameNode,name=FSNamesystemState"
+)
+
+// namenodeCollectors are the NameNode collectors besides RulesCollector. They
+// export what the rules cannot express, such as the JSON documents embedded
+// in string attributes.
+var namenodeCollectors = map[string]func(opts Options) scraper{
+	"datanodes":  func(Options) scraper { return scrapeFunc(collectDataNodes) },
+	"top_users":  newTopUsers,
+	"safemode":   func(Options) scraper { return scrapeFunc(collectSafemode) },
+	"checkpoint": func(Options) scraper { return scrapeFunc(collectCheckpoint) },
+	"snapshots":  func(Options) scraper { return scrapeFunc(collectSnapshots) },
+	"outliers":   func(Options) scraper { return scrapeFunc(collectOutliers) },
+}
+
+// scrapeFunc adapts a function to the scraper interface.
+type scrapeFunc func(beans []jmx.Bean, ch chan<- prometheus.Metric) []error
+
+func (f scrapeFunc) Collect(beans []jmx.Bean, ch chan<- prometheus.Metric) []error {
+	return f(beans, ch)
+}
+
+// findBean returns the bean with the given ObjectName.
+func findBean(beans []jmx.Bean, name string) (jmx.Bean, bool) {
+	return (&jmx.Response{Beans: beans}).Bean(name)
+}
+
+func gauge(ch chan<- prometheus.Metric, desc *prometheus.Desc, v float64, labels ...string) {
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, labels...)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
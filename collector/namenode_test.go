@@ -0,0 +1,103 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+// loadBeans reads the beans of a /jmx response captured in testdata.
+func loadBeans(t *testing.T, fixture string) []jmx.Bean {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp jmx.Response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatalf("%s: %v", fixture, err)
+	}
+	return resp.Beans
+}
+
+var fqNameRE = regexp.MustCompile(`fqName: "([^"]*)"`)
+
+// scrapeFixture runs s on the beans of fixture and returns the values of the
+// metrics it sent, keyed as by gather.
+func scrapeFixture(t *testing.T, s scraper, fixture string) (map[string]float64, []error) {
+	t.Helper()
+	beans := loadBeans(t, fixture)
+	var errs []error
+	metrics := gather(t, func(ch chan<- prometheus.Metric) {
+		errs = s.Collect(beans, ch)
+	})
+	return metrics, errs
+}
+
+// gather runs collect and returns the values of the metrics it sent, keyed
+// like the exposition format: name{label="value",...} with the labels sorted.
+func gather(t *testing.T, collect func(ch chan<- prometheus.Metric)) map[string]float64 {
+	t.Helper()
+	ch := make(chan prometheus.Metric)
+	go func() {
+		collect(ch)
+		close(ch)
+	}()
+	metrics := map[string]float64{}
+	for m := range ch {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatal(err)
+		}
+		key := fqNameRE.FindStringSubmatch(m.Desc().String())[1]
+		if len(pb.Label) > 0 {
+			var labels []string
+			for _, l := range pb.Label {
+				labels = append(labels, fmt.Sprintf("%s=%q", l.GetName(), l.GetValue()))
+			}
+			sort.Strings(labels)
+			key += "{" + strings.Join(labels, ",") + "}"
+		}
+		if _, ok := metrics[key]; ok {
+			t.Errorf("duplicate series %s", key)
+		}
+		switch {
+		case pb.Gauge != nil:
+			metrics[key] = pb.Gauge.GetValue()
+		case pb.Counter != nil:
+			metrics[key] = pb.Counter.GetValue()
+		default:
+			metrics[key] = pb.Untyped.GetValue()
+		}
+	}
+	return metrics
+}
+
+// checkMetrics reports the differences between the metrics got and want.
+func checkMetrics(t *testing.T, got, want map[string]float64) {
+	t.Helper()
+	for k, v := range want {
+		g, ok := got[k]
+		if !ok {
+			t.Errorf("missing %s", k)
+			continue
+		}
+		if g != v {
+			t.Errorf("%s = %v, want %v", k, g, v)
+		}
+	}
+	for k, v := range got {
+		if _, ok := want[k]; !ok {
+			t.Errorf("unexpected %s = %v", k, v)
+		}
+	}
+}
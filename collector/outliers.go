@@ -0,0 +1,126 @@
+package collector
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+// The slow DataNodes and disks detected by the outlier detection of Hadoop 3,
+// from the SlowPeersReport and SlowDisksReport JSON strings of the
+// NameNodeStatus bean. They are only reported when
+// dfs.datanode.peer.stats.enabled and
+// dfs.datanode.fileio.profiling.sampling.percentage are set.
+var (
+	slowPeerReportingNodesDesc = prometheus.NewDesc(
+		"namenode_slow_peer_reporting_nodes",
+		"Number of DataNodes reporting the DataNode as a slow peer.",
+		[]string{"node"}, nil,
+	)
+	slowPeerLatencyDesc = prometheus.NewDesc(
+		"namenode_slow_peer_latency_seconds",
+		"Latency of the slow DataNode as reported by a peer. Only reported by Hadoop 3.4 and later.",
+		[]string{"node", "reporting_node"}, nil,
+	)
+	slowDiskLatencyDesc = prometheus.NewDesc(
+		"namenode_slow_disk_latency_seconds",
+		"Average latency of the operations on the slow disk, identified by its DataNode and volume.",
+		[]string{"disk", "op"}, nil,
+	)
+)
+
+// The DataNode's own slow disks, from the SlowDisks JSON string of its
+// DataNodeInfo bean, which lists the volumes the DataNode reports to the
+// NameNode. It is null unless
+// dfs.datanode.fileio.profiling.sampling.percentage is set.
+var (
+	dataNodeSlowDisksDesc = prometheus.NewDesc(
+		"datanode_slow_disks",
+		"Number of the DataNode's volumes detected as slow disks.",
+		nil, nil,
+	)
+	dataNodeSlowDiskDesc = prometheus.NewDesc(
+		"datanode_slow_disk",
+		"1 for every volume of the DataNode detected as a slow disk.",
+		[]string{"disk"}, nil,
+	)
+)
+
+// slowPeer is an entry of SlowPeersReport. Releases before 3.4 only list
+// the reporting nodes, later ones their reported latency.
+type slowPeer struct {
+	SlowNode       string   `json:"SlowNode"`
+	ReportingNodes []string `json:"ReportingNodes"`
+	Latencies      []struct {
+		ReportingNode   string  `json:"ReportingNode"`
+		ReportedLatency float64 `json:"ReportedLatency"`
+	} `json:"SlowPeerLatencyWithReportingNodes"`
+}
+
+// slowDisk is an entry of SlowDisksReport. Latencies are in milliseconds,
+// keyed by READ, WRITE and METADATA.
+type slowDisk struct {
+	SlowDiskID string             `json:"SlowDiskID"`
+	Latencies  map[string]float64 `json:"Latencies"`
+}
+
+func collectOutliers(beans []jmx.Bean, ch chan<- prometheus.Metric) []error {
+	bean, ok := findBean(beans, nameNodeStatusBean)
+	if !ok {
+		return nil
+	}
+	var errs []error
+	var peers []slowPeer
+	if err := decodeReport(bean, "SlowPeersReport", &peers); err != nil {
+		errs = append(errs, err)
+	}
+	for _, p := range peers {
+		reporting := len(p.ReportingNodes)
+		if len(p.Latencies) > 0 {
+			reporting = len(p.Latencies)
+		}
+		gauge(ch, slowPeerReportingNodesDesc, float64(reporting), p.SlowNode)
+		for _, l := range p.Latencies {
+			gauge(ch, slowPeerLatencyDesc, l.ReportedLatency/1000, p.SlowNode, l.ReportingNode)
+		}
+	}
+	var disks []slowDisk
+	if err := decodeReport(bean, "SlowDisksReport", &disks); err != nil {
+		errs = append(errs, err)
+	}
+	for _, d := range disks {
+		for op, latency := range d.Latencies {
+			gauge(ch, slowDiskLatencyDesc, latency/1000, d.SlowDiskID, strings.ToLower(op))
+		}
+	}
+	return errs
+}
+
+func collectDataNodeOutliers(beans []jmx.Bean, ch chan<- prometheus.Metric) []error {
+	bean, ok := findBean(beans, dataNodeInfoBean)
+	if !ok {
+		return nil
+	}
+	if _, ok := bean.Attributes["SlowDisks"].(string); !ok {
+		return nil
+	}
+	var disks []string
+	if err := decodeReport(bean, "SlowDisks", &disks); err != nil {
+		return []error{err}
+	}
+	gauge(ch, dataNodeSlowDisksDesc, float64(len(disks)))
+	for _, d := range disks {
+		gauge(ch, dataNodeSlowDiskDesc, 1, d)
+	}
+	return nil
+}
+
+// decodeReport decodes an outlier report. Hadoop 2 has no reports, and
+// Hadoop 3 reports null when outlier detection is disabled.
+func decodeReport(bean jmx.Bean, attr string, v interface{}) error {
+	if s, ok := bean.Attributes[attr].(string); !ok || s == "" {
+		return nil
+	}
+	return bean.JSON(attr, v)
+}
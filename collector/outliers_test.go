@@ -0,0 +1,107 @@
+package collector
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+func TestCollectOutliers(t *testing.T) {
+	for _, tc := range []struct {
+		fixture string
+		want    map[string]float64
+	}{
+		{
+			// Hadoop 2 has no NameNodeStatus reports.
+			fixture: "namenode-2.7.json",
+			want:    map[string]float64{},
+		},
+		{
+			// Releases before 3.4 only list the reporting nodes.
+			fixture: "namenode-3.3.json",
+			want: map[string]float64{
+				`namenode_slow_peer_reporting_nodes{node="dn1.example.com:9866"}`:               2,
+				`namenode_slow_disk_latency_seconds{disk="dn2.example.com:/data/2",op="read"}`:  1.5,
+				`namenode_slow_disk_latency_seconds{disk="dn2.example.com:/data/2",op="write"}`: 0.25,
+			},
+		},
+		{
+			// SlowDisksReport is null when disk outlier detection is
+			// disabled.
+			fixture: "namenode-3.4.json",
+			want: map[string]float64{
+				`namenode_slow_peer_reporting_nodes{node="dn1.example.com:9866"}`:                                       2,
+				`namenode_slow_peer_latency_seconds{node="dn1.example.com:9866",reporting_node="dn2.example.com:9866"}`: 1.5,
+				`namenode_slow_peer_latency_seconds{node="dn1.example.com:9866",reporting_node="dn3.example.com:9866"}`: 0.9,
+			},
+		},
+	} {
+		t.Run(tc.fixture, func(t *testing.T) {
+			got, errs := scrapeFixture(t, scrapeFunc(collectOutliers), tc.fixture)
+			if len(errs) != 0 {
+				t.Errorf("errors: %v", errs)
+			}
+			checkMetrics(t, got, tc.want)
+		})
+	}
+}
+
+func TestCollectDataNodeOutliers(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		beans string
+		want  map[string]float64
+		err   string
+	}{
+		{
+			// Hadoop 2 has no SlowDisks.
+			name:  "hadoop 2",
+			beans: `{"name":"Hadoop:service=DataNode,name=DataNodeInfo","Version":"2.7.3"}`,
+			want:  map[string]float64{},
+		},
+		{
+			name:  "disabled",
+			beans: `{"name":"Hadoop:service=DataNode,name=DataNodeInfo","SlowDisks":null}`,
+			want:  map[string]float64{},
+		},
+		{
+			name:  "no slow disk",
+			beans: `{"name":"Hadoop:service=DataNode,name=DataNodeInfo","SlowDisks":"[]"}`,
+			want:  map[string]float64{"datanode_slow_disks": 0},
+		},
+		{
+			name:  "slow disks",
+			beans: `{"name":"Hadoop:service=DataNode,name=DataNodeInfo","SlowDisks":"[\"/data/2/dfs/dn\",\"/data/5/dfs/dn\"]"}`,
+			want: map[string]float64{
+				"datanode_slow_disks":                       2,
+				`datanode_slow_disk{disk="/data/2/dfs/dn"}`: 1,
+				`datanode_slow_disk{disk="/data/5/dfs/dn"}`: 1,
+			},
+		},
+		{
+			name:  "invalid",
+			beans: `{"name":"Hadoop:service=DataNode,name=DataNodeInfo","SlowDisks":"{"}`,
+			want:  map[string]float64{},
+			err:   "SlowDisks",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var resp jmx.Response
+			if err := json.Unmarshal([]byte(`{"beans":[`+tc.beans+`]}`), &resp); err != nil {
+				t.Fatal(err)
+			}
+			var errs []error
+			got := gather(t, func(ch chan<- prometheus.Metric) { errs = collectDataNodeOutliers(resp.Beans, ch) })
+			if tc.err == "" && len(errs) != 0 {
+				t.Errorf("errors: %v", errs)
+			}
+			if tc.err != "" && (len(errs) != 1 || !strings.Contains(errs[0].Error(), tc.err)) {
+				t.Errorf("errors = %v, want one about %s", errs, tc.err)
+			}
+			checkMetrics(t, got, tc.want)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"strings"
+
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+// fetchResourceManager returns the ResourceManager's cluster metrics and, if
+// the JVM collector is enabled, the beans of its /jmx. url is the
+// ResourceManager's web address. The documents are fetched independently,
+// and the beans of those that could be fetched are returned with a
+// fetchErrors listing the others.
+func fetchResourceManager(url string, opts Options) ([]jmx.Bean, error) {
+	url = strings.TrimSuffix(url, "/")
+	beans, err := fetchClusterMetrics(url, opts)
+	if !opts.enabled(JVMCollector) {
+		return beans, err
+	}
+	var errs fetchErrors
+	if err != nil {
+		errs = append(errs, err)
+	}
+	resp, err := opts.client().Fetch(url + "/jmx")
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		beans = append(beans, resp.Beans...)
+		errs = append(errs, resp.Skipped...)
+	}
+	if len(errs) == 0 {
+		return beans, nil
+	}
+	return beans, errs
+}
+
+// fetchClusterMetrics returns the ResourceManager's cluster metrics as a
+// single bean named clusterMetrics.
+func fetchClusterMetrics(url string, opts Options) ([]jmx.Bean, error) {
+	/*
+	  "clusterMetrics": {
+	    "activeNodes": 3,
+	    "rebootedNodes": 0,
+	    "decommissionedNodes": 0,
+	    "unhealthyNodes": 0,
+	    "lostNodes": 0,
+	    "totalNodes": 3,
+	    "totalVirtualCores": 9,
+	    "availableMB": 6144,
+	    "reservedMB": 0,
+	    "appsKilled": 0,
+	    "appsFailed": 1,
+	    "appsRunning": 0,
+	    "appsPending": 0,
+	    "appsCompleted": 9,
+	    "appsSubmitted": 10,
+	    "allocatedMB": 0,
+	    "reservedVirtualCores": 0,
+	    "availableVirtualCores": 9,
+	    "allocatedVirtualCores": 0,
+	    "containersAllocated": 0,
+	    "containersReserved": 0,
+	    "containersPending": 0,
+	    "totalMB": 6144
+	  }
+	*/
+	var body struct {
+		ClusterMetrics map[string]interface{} `json:"clusterMetrics"`
+	}
+	if err := opts.client().GetJSON(url+"/ws/v1/cluster/metrics", &body); err != nil {
+		return nil, err
+	}
+	return []jmx.Bean{{Name: "clusterMetrics", Attributes: body.ClusterMetrics}}, nil
+}
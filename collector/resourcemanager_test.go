@@ -0,0 +1,82 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResourceManager(t *testing.T) {
+	// Each prefix serves the cluster metrics under /ws/v1/cluster/metrics
+	// and the beans under /jmx, or fails one of them.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok/ws/v1/cluster/metrics", "/jmx-down/ws/v1/cluster/metrics":
+			fmt.Fprint(w, `{"clusterMetrics":{"activeNodes":3,"appsSubmitted":10}}`)
+		case "/ok/jmx", "/metrics-down/jmx":
+			fmt.Fprint(w, `{"beans":[{"name":"java.lang:type=ClassLoading","LoadedClassCount":9000}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	rules := map[string]float64{
+		"resourcemanager_active_nodes":         3,
+		"resourcemanager_apps_submitted_total": 10,
+	}
+	jvm := map[string]float64{"resourcemanager_jvm_classes_loaded": 9000}
+	for _, tc := range []struct {
+		name       string
+		path       string
+		collectors []string
+		want       map[string]float64
+	}{
+		{
+			name: "ok",
+			path: "/ok",
+			want: merge(rules, jvm, map[string]float64{"resourcemanager_up": 1}),
+		},
+		{
+			name: "jmx down",
+			path: "/jmx-down",
+			want: merge(rules, map[string]float64{
+				"resourcemanager_up": 1,
+				`resourcemanager_scrape_errors_total{reason="status"}`: 1,
+			}),
+		},
+		{
+			name: "cluster metrics down",
+			path: "/metrics-down",
+			want: merge(jvm, map[string]float64{
+				"resourcemanager_up": 1,
+				`resourcemanager_scrape_errors_total{reason="status"}`: 1,
+			}),
+		},
+		{
+			// Without the jvm collector, /jmx is not fetched.
+			name:       "rules only",
+			path:       "/jmx-down",
+			collectors: []string{"rules"},
+			want:       merge(rules, map[string]float64{"resourcemanager_up": 1}),
+		},
+		{
+			name:       "rules only, cluster metrics down",
+			path:       "/metrics-down",
+			collectors: []string{"rules"},
+			want: map[string]float64{
+				"resourcemanager_up": 0,
+				`resourcemanager_scrape_errors_total{reason="status"}`: 1,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := New("resourcemanager", srv.URL+tc.path, Options{Collectors: tc.collectors})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkMetrics(t, gather(t, e.Collect), tc.want)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package collector
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+// The progress of safe mode towards its block threshold, parsed from the
+// message in NameNodeInfo's Safemode, which is empty out of safe mode. Safe
+// mode itself is exported by the rules from FSNamesystemState's FSState.
+var (
+	safemodeManualDesc = prometheus.NewDesc(
+		"namenode_safemode_manual",
+		"Whether safe mode was entered manually or because resources are low, in which case the NameNode will not leave it by itself.",
+		nil, nil,
+	)
+	safemodeReportedBlocksDesc = prometheus.NewDesc(
+		"namenode_safemode_reported_blocks",
+		"Number of blocks with enough replicas reported by the DataNodes.",
+		nil, nil,
+	)
+	safemodeThresholdBlocksDesc = prometheus.NewDesc(
+		"namenode_safemode_threshold_blocks",
+		"Number of reported blocks needed to leave safe mode.",
+		nil, nil,
+	)
+	safemodeTotalBlocksDesc = prometheus.NewDesc(
+		"namenode_safemode_total_blocks",
+		"Number of complete blocks in the namespace.",
+		nil, nil,
+	)
+)
+
+// The messages of Hadoop 2 and 3 agree on the part about blocks, e.g.
+// "The reported blocks 10 needs additional 2 blocks to reach the threshold
+// 0.9990 of total blocks 12." or "The reported blocks 12 has reached the
+// threshold 0.9990 of total blocks 12."
+var (
+	safemodeBlocksRE = regexp.MustCompile(`The reported blocks (\d+) (?:needs additional (\d+) blocks to reach|has reached) the threshold ([\d.]+) of total blocks (\d+)`)
+	safemodeManualRE = regexp.MustCompile(`turned on manually|[Rr]esources are low`)
+)
+
+func collectSafemode(beans []jmx.Bean, ch chan<- prometheus.Metric) []error {
+	bean, ok := findBean(beans, nameNodeInfoBean)
+	if !ok {
+		return nil
+	}
+	msg, err := bean.String("Safemode")
+	if err != nil {
+		return []error{err}
+	}
+	msg = strings.TrimSpace(msg)
+	if msg == "" {
+		return nil
+	}
+	gauge(ch, safemodeManualDesc, boolToFloat(safemodeManualRE.MatchString(msg)))
+	m := safemodeBlocksRE.FindStringSubmatch(msg)
+	if m == nil {
+		// Safe mode entered manually does not wait for blocks.
+		return nil
+	}
+	reported, _ := strconv.ParseFloat(m[1], 64)
+	ratio, _ := strconv.ParseFloat(m[3], 64)
+	total, _ := strconv.ParseFloat(m[4], 64)
+	threshold := math.Floor(total * ratio)
+	if m[2] != "" {
+		needed, _ := strconv.ParseFloat(m[2], 64)
+		threshold = reported + needed
+	}
+	gauge(ch, safemodeReportedBlocksDesc, reported)
+	gauge(ch, safemodeThresholdBlocksDesc, threshold)
+	gauge(ch, safemodeTotalBlocksDesc, total)
+	return nil
+}
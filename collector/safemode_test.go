@@ -0,0 +1,40 @@
+package collector
+
+import "testing"
+
+func TestCollectSafemode(t *testing.T) {
+	for _, tc := range []struct {
+		fixture string
+		want    map[string]float64
+	}{
+		{
+			// Out of safe mode, Safemode is empty.
+			fixture: "namenode-2.7.json",
+			want:    map[string]float64{},
+		},
+		{
+			fixture: "namenode-3.3.json",
+			want: map[string]float64{
+				"namenode_safemode_manual":           0,
+				"namenode_safemode_reported_blocks":  10,
+				"namenode_safemode_threshold_blocks": 12,
+				"namenode_safemode_total_blocks":     12,
+			},
+		},
+		{
+			// Safe mode entered manually does not wait for blocks.
+			fixture: "namenode-3.4.json",
+			want: map[string]float64{
+				"namenode_safemode_manual": 1,
+			},
+		},
+	} {
+		t.Run(tc.fixture, func(t *testing.T) {
+			got, errs := scrapeFixture(t, scrapeFunc(collectSafemode), tc.fixture)
+			if len(errs) != 0 {
+				t.Errorf("errors: %v", errs)
+			}
+			checkMetrics(t, got, tc.want)
+		})
+	}
+}
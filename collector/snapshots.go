@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+const snapshotInfoBean = "Hadoop:service=NameNode,name=SnapshotInfo"
+
+// Snapshots per snapshottable directory, from the SnapshotInfo bean, labelled
+// by the directory's path. The totals are exported by the rules from
+// FSNamesystemState.
+var (
+	snapshotDirSnapshotsDesc = prometheus.NewDesc(
+		"namenode_snapshottable_directory_snapshots",
+		"Number of snapshots of the snapshottable directory.",
+		[]string{"path"}, nil,
+	)
+	snapshotDirQuotaDesc = prometheus.NewDesc(
+		"namenode_snapshottable_directory_snapshot_quota",
+		"Maximum number of snapshots of the snapshottable directory.",
+		[]string{"path"}, nil,
+	)
+	snapshotDirOldestDesc = prometheus.NewDesc(
+		"namenode_snapshottable_directory_oldest_snapshot_timestamp_seconds",
+		"Modification time of the oldest snapshot of the snapshottable directory, as a Unix timestamp.",
+		[]string{"path"}, nil,
+	)
+)
+
+// snapshottableDirectory is an entry of SnapshotInfo's
+// SnapshottableDirectories.
+type snapshottableDirectory struct {
+	Path           string  `json:"path"`
+	SnapshotNumber float64 `json:"snapshotNumber"`
+	SnapshotQuota  float64 `json:"snapshotQuota"`
+}
+
+// snapshot is an entry of SnapshotInfo's Snapshots. snapshotDirectory is the
+// snapshot's path, such as /data/.snapshot/s1.
+type snapshot struct {
+	SnapshotDirectory string  `json:"snapshotDirectory"`
+	ModificationTime  float64 `json:"modificationTime"`
+}
+
+func collectSnapshots(beans []jmx.Bean, ch chan<- prometheus.Metric) []error {
+	bean, ok := findBean(beans, snapshotInfoBean)
+	if !ok {
+		return nil
+	}
+	var errs []error
+	var dirs []snapshottableDirectory
+	if err := bean.Decode("SnapshottableDirectories", &dirs); err != nil {
+		errs = append(errs, err)
+	}
+	var snapshots []snapshot
+	if err := bean.Decode("Snapshots", &snapshots); err != nil {
+		errs = append(errs, err)
+	}
+	// Modification times are in milliseconds.
+	oldest := map[string]float64{}
+	for _, s := range snapshots {
+		i := strings.LastIndex(s.SnapshotDirectory, "/.snapshot/")
+		if i < 0 {
+			continue
+		}
+		dir := s.SnapshotDirectory[:i]
+		if dir == "" {
+			dir = "/"
+		}
+		if t, ok := oldest[dir]; !ok || s.ModificationTime < t {
+			oldest[dir] = s.ModificationTime
+		}
+	}
+	for _, d := range dirs {
+		gauge(ch, snapshotDirSnapshotsDesc, d.SnapshotNumber, d.Path)
+		gauge(ch, snapshotDirQuotaDesc, d.SnapshotQuota, d.Path)
+		if t, ok := oldest[d.Path]; ok {
+			gauge(ch, snapshotDirOldestDesc, t/1000, d.Path)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+func TestCollectSnapshots(t *testing.T) {
+	for _, tc := range []struct {
+		fixture string
+		want    map[string]float64
+	}{
+		{
+			// Without a SnapshotInfo bean, nothing is exported.
+			fixture: "namenode-2.7.json",
+			want:    map[string]float64{},
+		},
+		{
+			// The oldest snapshot of a directory is found from the paths of
+			// the snapshots, which do not match those of its ancestors.
+			fixture: "namenode-3.3.json",
+			want: map[string]float64{
+				`namenode_snapshottable_directory_snapshots{path="/"}`:                                        1,
+				`namenode_snapshottable_directory_snapshot_quota{path="/"}`:                                   65536,
+				`namenode_snapshottable_directory_oldest_snapshot_timestamp_seconds{path="/"}`:                1714000000,
+				`namenode_snapshottable_directory_snapshots{path="/warehouse/sales"}`:                         2,
+				`namenode_snapshottable_directory_snapshot_quota{path="/warehouse/sales"}`:                    65536,
+				`namenode_snapshottable_directory_oldest_snapshot_timestamp_seconds{path="/warehouse/sales"}`: 1714435200.5,
+				`namenode_snapshottable_directory_snapshots{path="/warehouse"}`:                               0,
+				`namenode_snapshottable_directory_snapshot_quota{path="/warehouse"}`:                          10,
+			},
+		},
+	} {
+		t.Run(tc.fixture, func(t *testing.T) {
+			got, errs := scrapeFixture(t, scrapeFunc(collectSnapshots), tc.fixture)
+			if len(errs) != 0 {
+				t.Errorf("errors: %v", errs)
+			}
+			checkMetrics(t, got, tc.want)
+		})
+	}
+}
+
+func TestCollectSnapshotsErrors(t *testing.T) {
+	// Snapshots cannot be decoded, so the directories are exported without
+	// their oldest snapshot.
+	var resp jmx.Response
+	if err := json.Unmarshal([]byte(`{"beans":[{
+		"name": "Hadoop:service=NameNode,name=SnapshotInfo",
+		"SnapshottableDirectories": [{"path": "/data", "snapshotNumber": 1, "snapshotQuota": 65536}],
+		"Snapshots": "unavailable"
+	}]}`), &resp); err != nil {
+		t.Fatal(err)
+	}
+	var errs []error
+	got := gather(t, func(ch chan<- prometheus.Metric) { errs = collectSnapshots(resp.Beans, ch) })
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "Snapshots") {
+		t.Errorf("errors = %v, want one about Snapshots", errs)
+	}
+	checkMetrics(t, got, map[string]float64{
+		`namenode_snapshottable_directory_snapshots{path="/data"}`:      1,
+		`namenode_snapshottable_directory_snapshot_quota{path="/data"}`: 65536,
+	})
+}
@@ -0,0 +1,57 @@
+package collector
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+// certRecorder records the earliest expiry among the certificates presented
+// by the HTTPS endpoints it is used for.
+type certRecorder struct {
+	rt http.RoundTripper
+
+	mu     sync.Mutex
+	expiry time.Time
+}
+
+// client returns a copy of c recording the certificates into r.
+func (r *certRecorder) client(c *jmx.Client) *jmx.Client {
+	hc := *c.HTTPClient
+	r.rt = hc.Transport
+	if r.rt == nil {
+		r.rt = http.DefaultTransport
+	}
+	hc.Transport = r
+	return &jmx.Client{HTTPClient: &hc}
+}
+
+func (r *certRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.rt.RoundTrip(req)
+	if err != nil || resp.TLS == nil {
+		return resp, err
+	}
+	for _, cert := range resp.TLS.PeerCertificates {
+		r.record(cert.NotAfter)
+	}
+	return resp, nil
+}
+
+// record records the expiry of a certificate. Zero times are ignored.
+func (r *certRecorder) record(expiry time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !expiry.IsZero() && (r.expiry.IsZero() || expiry.Before(r.expiry)) {
+		r.expiry = expiry
+	}
+}
+
+// earliestExpiry returns the earliest expiry recorded, and false if no
+// certificate was seen.
+func (r *certRecorder) earliestExpiry() (time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.expiry, !r.expiry.IsZero()
+}
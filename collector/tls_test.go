@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+func TestCertRecorder(t *testing.T) {
+	r := &certRecorder{}
+	if _, ok := r.earliestExpiry(); ok {
+		t.Error("expiry recorded without certificates")
+	}
+	first := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, expiry := range []time.Time{first.Add(time.Hour), first, {}, first.Add(2 * time.Hour)} {
+		r.record(expiry)
+	}
+	if got, ok := r.earliestExpiry(); !ok || !got.Equal(first) {
+		t.Errorf("earliestExpiry() = %v, %v, want %v", got, ok, first)
+	}
+}
+
+func TestCertExpiry(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"beans":[{"name":"Hadoop:service=DataNode,name=DataNodeInfo"}]}`)
+	})
+	tlsSrv := httptest.NewTLSServer(handler)
+	defer tlsSrv.Close()
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := &jmx.Client{HTTPClient: tlsSrv.Client()}
+	expiry := float64(tlsSrv.Certificate().NotAfter.Unix())
+
+	for _, tc := range []struct {
+		name string
+		url  string
+		// doc is set to scrape a document fetched beforehand.
+		doc  bool
+		want map[string]float64
+	}{
+		{
+			name: "https",
+			url:  tlsSrv.URL + "/jmx",
+			want: map[string]float64{
+				"datanode_up": 1,
+				"datanode_tls_cert_expiry_timestamp_seconds": expiry,
+			},
+		},
+		{
+			// The certificate was presented when the document was fetched.
+			name: "https document",
+			url:  tlsSrv.URL + "/jmx",
+			doc:  true,
+			want: map[string]float64{
+				"datanode_up": 1,
+				"datanode_tls_cert_expiry_timestamp_seconds": expiry,
+			},
+		},
+		{
+			name: "http",
+			url:  srv.URL + "/jmx",
+			want: map[string]float64{"datanode_up": 1},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := New("datanode", tc.url, Options{Collectors: []string{"rules"}, Client: client})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc *Document
+			if tc.doc {
+				if doc, err = FetchDocument(tc.url, client); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got := gather(t, func(ch chan<- prometheus.Metric) { e.ScrapeDocument(doc, ch) })
+			checkMetrics(t, got, tc.want)
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+// DefaultTopUsers is the number of users exported per operation and RPC
+// server when Options.TopUsers is not positive.
+const DefaultTopUsers = 10
+
+// The heaviest users of the NameNode, from nntop (FSNamesystemState's
+// TopUserOpCounts) and from the DecayRpcScheduler of RPC servers using the
+// FairCallQueue.
+var (
+	topUserOpsDesc = prometheus.NewDesc(
+		"namenode_top_user_ops",
+		"Operations of the user in the window, for the users with the most operations of each type according to nntop.",
+		[]string{"window", "op", "user"}, nil,
+	)
+	topOpsDesc = prometheus.NewDesc(
+		"namenode_top_ops",
+		"Operations of each type in the window, by all users, according to nntop. The op * counts every type.",
+		[]string{"window", "op"}, nil,
+	)
+	callerVolumeDesc = prometheus.NewDesc(
+		"namenode_rpc_caller_volume",
+		"Decayed call volume of the user on the RPC server, for the users with the highest volume.",
+		[]string{"port", "user"}, nil,
+	)
+	callerPriorityDesc = prometheus.NewDesc(
+		"namenode_rpc_caller_priority",
+		"Priority the DecayRpcScheduler assigns to the user's calls on the RPC server, 0 being the highest.",
+		[]string{"port", "user"}, nil,
+	)
+)
+
+var (
+	decayRpcSchedulerRE = regexp.MustCompile(`^Hadoop:service=NameNode,name=DecayRpcSchedulerMetrics2\.ipc\.(\d+)$`)
+	callerRE            = regexp.MustCompile(`^Caller\((.+)\)\.(Volume|Priority)$`)
+)
+
+// topUserOpCounts is the document in FSNamesystemState's TopUserOpCounts.
+type topUserOpCounts struct {
+	Windows []struct {
+		WindowLenMs float64 `json:"windowLenMs"`
+		Ops         []struct {
+			OpType     string  `json:"opType"`
+			TotalCount float64 `json:"totalCount"`
+			TopUsers   []struct {
+				User  string  `json:"user"`
+				Count float64 `json:"count"`
+			} `json:"topUsers"`
+		} `json:"ops"`
+	} `json:"windows"`
+}
+
+// topUsers exports the users loading the NameNode the most, keeping at
+// most limit users per operation and RPC server to bound cardinality.
+type topUsers struct {
+	limit int
+}
+
+func newTopUsers(opts Options) scraper {
+	limit := opts.TopUsers
+	if limit <= 0 {
+		limit = DefaultTopUsers
+	}
+	return topUsers{limit: limit}
+}
+
+func (t topUsers) Collect(beans []jmx.Bean, ch chan<- prometheus.Metric) []error {
+	var errs []error
+	if bean, ok := findBean(beans, fsNamesystemStateBean); ok {
+		if err := t.collectTop(bean, ch); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, bean := range beans {
+		if m := decayRpcSchedulerRE.FindStringSubmatch(bean.Name); m != nil {
+			t.collectCallers(bean, m[1], ch)
+		}
+	}
+	return errs
+}
+
+func (t topUsers) collectTop(bean jmx.Bean, ch chan<- prometheus.Metric) error {
+	if _, ok := bean.Attributes["TopUserOpCounts"]; !ok {
+		// nntop is disabled (dfs.namenode.top.enabled=false).
+		return nil
+	}
+	var top topUserOpCounts
+	if err := bean.JSON("TopUserOpCounts", &top); err != nil {
+		return err
+	}
+	for _, w := range top.Windows {
+		window := fmt.Sprintf("%.0fs", w.WindowLenMs/1000)
+		for _, op := range w.Ops {
+			gauge(ch, topOpsDesc, op.TotalCount, window, op.OpType)
+			users := op.TopUsers
+			sort.SliceStable(users, func(i, j int) bool { return users[i].Count > users[j].Count })
+			if len(users) > t.limit {
+				users = users[:t.limit]
+			}
+			for _, u := range users {
+				gauge(ch, topUserOpsDesc, u.Count, window, op.OpType, u.User)
+			}
+		}
+	}
+	return nil
+}
+
+type caller struct {
+	user     string
+	volume   float64
+	priority float64
+	// hasPriority is false if the bean has no Priority for the user.
+	hasPriority bool
+}
+
+func (t topUsers) collectCallers(bean jmx.Bean, port string, ch chan<- prometheus.Metric) {
+	byUser := map[string]*caller{}
+	for attr, v := range bean.Attributes {
+		m := callerRE.FindStringSubmatch(attr)
+		f, ok := v.(float64)
+		if m == nil || !ok {
+			continue
+		}
+		c := byUser[m[1]]
+		if c == nil {
+			c = &caller{user: m[1]}
+			byUser[m[1]] = c
+		}
+		if m[2] == "Volume" {
+			c.volume = f
+		} else {
+			c.priority, c.hasPriority = f, true
+		}
+	}
+	callers := make([]*caller, 0, len(byUser))
+	for _, c := range byUser {
+		callers = append(callers, c)
+	}
+	sort.Slice(callers, func(i, j int) bool {
+		if callers[i].volume != callers[j].volume {
+			return callers[i].volume > callers[j].volume
+		}
+		return callers[i].user < callers[j].user
+	})
+	if len(callers) > t.limit {
+		callers = callers[:t.limit]
+	}
+	for _, c := range callers {
+		gauge(ch, callerVolumeDesc, c.volume, port, c.user)
+		if c.hasPriority {
+			gauge(ch, callerPriorityDesc, c.priority, port, c.user)
+		}
+	}
+}
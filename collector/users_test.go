@@ -0,0 +1,77 @@
+package collector
+
+import "testing"
+
+func TestTopUsers(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		fixture  string
+		topUsers int
+		want     map[string]float64
+	}{
+		{
+			name:    "nntop",
+			fixture: "namenode-2.7.json",
+			want: map[string]float64{
+				`namenode_top_ops{op="create",window="60s"}`:                  50,
+				`namenode_top_ops{op="*",window="60s"}`:                       110,
+				`namenode_top_ops{op="*",window="300s"}`:                      300,
+				`namenode_top_user_ops{op="create",user="etl",window="60s"}`:  40,
+				`namenode_top_user_ops{op="create",user="hive",window="60s"}`: 9,
+				`namenode_top_user_ops{op="create",user="hdfs",window="60s"}`: 1,
+				`namenode_top_user_ops{op="*",user="hive",window="60s"}`:      70,
+				`namenode_top_user_ops{op="*",user="etl",window="60s"}`:       40,
+				`namenode_top_user_ops{op="*",user="hive",window="300s"}`:     300,
+			},
+		},
+		{
+			name:     "nntop limited",
+			fixture:  "namenode-2.7.json",
+			topUsers: 1,
+			want: map[string]float64{
+				`namenode_top_ops{op="create",window="60s"}`:                 50,
+				`namenode_top_ops{op="*",window="60s"}`:                      110,
+				`namenode_top_ops{op="*",window="300s"}`:                     300,
+				`namenode_top_user_ops{op="create",user="etl",window="60s"}`: 40,
+				`namenode_top_user_ops{op="*",user="hive",window="60s"}`:     70,
+				`namenode_top_user_ops{op="*",user="hive",window="300s"}`:    300,
+			},
+		},
+		{
+			// hdfs has a volume but no priority yet.
+			name:    "callers",
+			fixture: "namenode-3.3.json",
+			want: map[string]float64{
+				`namenode_rpc_caller_volume{port="8020",user="etl"}`:    90,
+				`namenode_rpc_caller_volume{port="8020",user="hive"}`:   8,
+				`namenode_rpc_caller_volume{port="8020",user="hdfs"}`:   2,
+				`namenode_rpc_caller_priority{port="8020",user="etl"}`:  3,
+				`namenode_rpc_caller_priority{port="8020",user="hive"}`: 1,
+			},
+		},
+		{
+			name:     "callers limited",
+			fixture:  "namenode-3.3.json",
+			topUsers: 2,
+			want: map[string]float64{
+				`namenode_rpc_caller_volume{port="8020",user="etl"}`:    90,
+				`namenode_rpc_caller_volume{port="8020",user="hive"}`:   8,
+				`namenode_rpc_caller_priority{port="8020",user="etl"}`:  3,
+				`namenode_rpc_caller_priority{port="8020",user="hive"}`: 1,
+			},
+		},
+		{
+			name:    "nntop disabled",
+			fixture: "namenode-3.4.json",
+			want:    map[string]float64{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, errs := scrapeFixture(t, newTopUsers(Options{TopUsers: tc.topUsers}), tc.fixture)
+			if len(errs) != 0 {
+				t.Errorf("errors: %v", errs)
+			}
+			checkMetrics(t, got, tc.want)
+		})
+	}
+}
@@ -0,0 +1,322 @@
+// Package config loads the exporter's configuration file, which describes
+// the Hadoop clusters to scrape and how to reach their daemons.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"sort"
+
+	"github.com/wyukawa/hadoop_exporter/httpclient"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultTimeout is the request timeout of targets that do not set one.
+const DefaultTimeout = jmx.DefaultTimeout
+
+// Config is the top-level configuration.
+type Config struct {
+	// Global holds the defaults of every cluster.
+	Global   Settings  `yaml:"global"`
+	Clusters []Cluster `yaml:"clusters"`
+}
+
+// Settings can be given globally, per cluster and per target. A target
+// inherits the settings of its cluster, which inherits the global ones; the
+// most specific value wins and labels are merged.
+type Settings struct {
+	httpclient.Config `yaml:",inline"`
+	// Collectors lists the collectors enabled for the target. All collectors
+	// of the role are enabled if it is empty.
+	Collectors []string `yaml:"collectors"`
+	// TopUsers caps the users exported per operation and RPC server by the
+	// NameNode's top_users collector.
+	TopUsers int `yaml:"top_users"`
+	// Labels are added to every metric of the target. Targets also get a
+	// cluster and a target label, holding the names of the cluster and the
+	// target, unless Labels sets them.
+	Labels map[string]string `yaml:"labels"`
+}
+
+// Cluster groups the daemons of one Hadoop cluster by role.
+type Cluster struct {
+	Name              string `yaml:"name"`
+	Settings          `yaml:",inline"`
+	NameNodes         []Target `yaml:"namenodes"`
+	DataNodes         []Target `yaml:"datanodes"`
+	JournalNodes      []Target `yaml:"journalnodes"`
+	ResourceManagers  []Target `yaml:"resourcemanagers"`
+	NodeManagers      []Target `yaml:"nodemanagers"`
+	JobHistoryServers []Target `yaml:"jobhistoryservers"`
+}
+
+// Target is one daemon to scrape.
+type Target struct {
+	// Name identifies the target within its cluster and role. It defaults
+	// to the host and port of URL.
+	Name string `yaml:"name"`
+	// URL is the daemon's /jmx URL, or the web address of a ResourceManager.
+	URL string `yaml:"url"`
+	// Journal selects the journal exported by a JournalNode. All journals
+	// are exported if it is empty.
+	Journal string `yaml:"journal"`
+	// NameService groups the NameNodes whose HA state is watched together.
+	// It defaults to the cluster's only nameservice.
+	NameService string `yaml:"nameservice"`
+	Settings    `yaml:",inline"`
+
+	// Cluster and Role are set by Targets.
+	Cluster string `yaml:"-"`
+	Role    string `yaml:"-"`
+}
+
+// roles returns the targets of c by role.
+func (c *Cluster) roles() map[string][]Target {
+	return map[string][]Target{
+		"namenode":         c.NameNodes,
+		"datanode":         c.DataNodes,
+		"journalnode":      c.JournalNodes,
+		"resourcemanager":  c.ResourceManagers,
+		"nodemanager":      c.NodeManagers,
+		"jobhistoryserver": c.JobHistoryServers,
+	}
+}
+
+// Load reads and validates the configuration file at path.
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := yaml.UnmarshalStrict(data, &c); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &c, nil
+}
+
+func (c *Config) validate() error {
+	if err := c.Global.validate(); err != nil {
+		return fmt.Errorf("global: %v", err)
+	}
+	if len(c.Clusters) == 0 {
+		return errors.New("no clusters configured")
+	}
+	clusters := map[string]bool{}
+	for i := range c.Clusters {
+		cluster := &c.Clusters[i]
+		if cluster.Name == "" {
+			return fmt.Errorf("cluster %d: name is required", i)
+		}
+		if clusters[cluster.Name] {
+			return fmt.Errorf("cluster %s: duplicate name", cluster.Name)
+		}
+		clusters[cluster.Name] = true
+		if err := cluster.validate(); err != nil {
+			return fmt.Errorf("cluster %s: %v", cluster.Name, err)
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) validate() error {
+	if err := c.Settings.validate(); err != nil {
+		return err
+	}
+	// The roles are validated in a fixed order, so that the same error is
+	// reported for the same file.
+	roles := c.roles()
+	var sorted []string
+	for role := range roles {
+		sorted = append(sorted, role)
+	}
+	sort.Strings(sorted)
+	for _, role := range sorted {
+		targets := roles[role]
+		names := map[string]bool{}
+		for i := range targets {
+			t := &targets[i]
+			if t.URL == "" {
+				return fmt.Errorf("%s %d: url is required", role, i)
+			}
+			u, err := url.Parse(t.URL)
+			if err != nil {
+				return fmt.Errorf("%s %d: %v", role, i, err)
+			}
+			if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%s %d: url %q must be an absolute http or https URL", role, i, t.URL)
+			}
+			if t.Name == "" {
+				t.Name = u.Host
+			}
+			if names[t.Name] {
+				return fmt.Errorf("%s %s: duplicate name", role, t.Name)
+			}
+			names[t.Name] = true
+			if err := t.Settings.validate(); err != nil {
+				return fmt.Errorf("%s %s: %v", role, t.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+var labelNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func (s *Settings) validate() error {
+	if s.TopUsers < 0 {
+		return errors.New("top_users must not be negative")
+	}
+	for name := range s.Labels {
+		if !labelNameRE.MatchString(name) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+	}
+	return s.Config.Validate()
+}
+
+// merge returns s with the values set in override taking precedence.
+func (s Settings) merge(override Settings) Settings {
+	if override.Timeout != 0 {
+		s.Timeout = override.Timeout
+	}
+	if override.BasicAuth != nil {
+		s.BasicAuth = override.BasicAuth
+	}
+	if override.TLSConfig != nil {
+		s.TLSConfig = override.TLSConfig
+	}
+	// A target uses one authentication scheme, so setting one replaces the
+	// other.
+	if override.BasicAuth != nil {
+		s.Kerberos = nil
+	}
+	if override.Kerberos != nil {
+		s.Kerberos = override.Kerberos
+		s.BasicAuth = nil
+	}
+	if override.UserName != "" {
+		s.UserName = override.UserName
+	}
+	if override.DelegationTokenFile != "" {
+		s.DelegationTokenFile = override.DelegationTokenFile
+	}
+	if override.TopUsers != 0 {
+		s.TopUsers = override.TopUsers
+	}
+	if len(override.Collectors) != 0 {
+		s.Collectors = override.Collectors
+	}
+	labels := map[string]string{}
+	for k, v := range s.Labels {
+		labels[k] = v
+	}
+	for k, v := range override.Labels {
+		labels[k] = v
+	}
+	s.Labels = labels
+	return s
+}
+
+// NameService is a group of NameNodes watched together for failovers.
+type NameService struct {
+	Cluster string
+	// Name is empty for clusters without federation.
+	Name string
+	// Labels are the cluster's labels, with cluster and nameservice labels
+	// holding the names of the cluster and of the nameservice.
+	Labels    map[string]string
+	NameNodes []Target
+}
+
+// NameServices returns the nameservices of every cluster, with the settings
+// of their NameNodes resolved like Targets.
+func (c *Config) NameServices() []NameService {
+	var nameservices []NameService
+	index := map[[2]string]int{}
+	for _, t := range c.Targets("namenode") {
+		key := [2]string{t.Cluster, t.NameService}
+		i, ok := index[key]
+		if !ok {
+			i = len(nameservices)
+			index[key] = i
+			labels := map[string]string{}
+			for _, cluster := range c.Clusters {
+				if cluster.Name == t.Cluster {
+					labels = c.Global.merge(cluster.Settings).Labels
+				}
+			}
+			if _, ok := labels["cluster"]; !ok {
+				labels["cluster"] = t.Cluster
+			}
+			// The label is set even if empty, so that every nameservice
+			// has the same label names.
+			if _, ok := labels["nameservice"]; !ok {
+				labels["nameservice"] = t.NameService
+			}
+			nameservices = append(nameservices, NameService{Cluster: t.Cluster, Name: t.NameService, Labels: labels})
+		}
+		nameservices[i].NameNodes = append(nameservices[i].NameNodes, t)
+	}
+	labels := make([]map[string]string, len(nameservices))
+	for i, ns := range nameservices {
+		labels[i] = ns.Labels
+	}
+	alignLabels(labels)
+	return nameservices
+}
+
+// Targets returns the targets of role in every cluster, with their settings
+// resolved.
+func (c *Config) Targets(role string) []Target {
+	var targets []Target
+	for _, cluster := range c.Clusters {
+		settings := c.Global.merge(cluster.Settings)
+		for _, t := range cluster.roles()[role] {
+			t.Settings = settings.merge(t.Settings)
+			if t.Timeout == 0 {
+				t.Timeout = DefaultTimeout
+			}
+			if _, ok := t.Labels["cluster"]; !ok {
+				t.Labels["cluster"] = cluster.Name
+			}
+			if _, ok := t.Labels["target"]; !ok {
+				t.Labels["target"] = t.Name
+			}
+			t.Cluster = cluster.Name
+			t.Role = role
+			targets = append(targets, t)
+		}
+	}
+	labels := make([]map[string]string, len(targets))
+	for i, t := range targets {
+		labels[i] = t.Labels
+	}
+	alignLabels(labels)
+	return targets
+}
+
+// alignLabels adds the label names missing from some of labels with empty
+// values, since the metrics of a family must all have the same label names.
+func alignLabels(labels []map[string]string) {
+	names := map[string]bool{}
+	for _, l := range labels {
+		for name := range l {
+			names[name] = true
+		}
+	}
+	for _, l := range labels {
+		for name := range names {
+			if _, ok := l[name]; !ok {
+				l[name] = ""
+			}
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wyukawa/hadoop_exporter/httpclient"
+)
+
+// load writes a configuration file holding yml and loads it.
+func load(t *testing.T, yml string) (*Config, error) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yml")
+	if err := ioutil.WriteFile(path, []byte(yml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		// The path is the same for every case.
+		return nil, errors.New(strings.TrimPrefix(err.Error(), path+": "))
+	}
+	return c, nil
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		yml  string
+		err  string
+	}{
+		{
+			name: "no clusters",
+			yml:  "global: {timeout: 5s}\n",
+			err:  "no clusters configured",
+		},
+		{
+			name: "unknown field",
+			yml:  "clusters: [{name: a, namenode: []}]\n",
+			err:  "yaml: unmarshal errors:",
+		},
+		{
+			name: "global settings",
+			yml:  "global: {top_users: -1}\nclusters: [{name: a}]\n",
+			err:  "global: top_users must not be negative",
+		},
+		{
+			name: "cluster without name",
+			yml:  "clusters: [{namenodes: [{url: 'http://nn:9870/jmx'}]}]\n",
+			err:  "cluster 0: name is required",
+		},
+		{
+			name: "duplicate cluster",
+			yml:  "clusters: [{name: a}, {name: a}]\n",
+			err:  "cluster a: duplicate name",
+		},
+		{
+			name: "target without url",
+			yml:  "clusters: [{name: a, datanodes: [{name: dn1}]}]\n",
+			err:  "cluster a: datanode 0: url is required",
+		},
+		{
+			name: "relative url",
+			yml:  "clusters: [{name: a, datanodes: [{url: 'dn1:9864/jmx'}]}]\n",
+			err:  `cluster a: datanode 0: url "dn1:9864/jmx" must be an absolute http or https URL`,
+		},
+		{
+			// Names default to the host and port of the URL.
+			name: "duplicate target",
+			yml:  "clusters: [{name: a, datanodes: [{url: 'http://dn1:9864/jmx'}, {url: 'http://dn1:9864/jmx'}]}]\n",
+			err:  "cluster a: datanode dn1:9864: duplicate name",
+		},
+		{
+			name: "target settings",
+			yml:  "clusters: [{name: a, namenodes: [{url: 'http://nn:9870/jmx', labels: {a-b: c}}]}]\n",
+			err:  `cluster a: namenode nn:9870: invalid label name "a-b"`,
+		},
+		{
+			name: "client settings",
+			yml:  "clusters: [{name: a, basic_auth: {password: p}}]\n",
+			err:  "cluster a: basic_auth: username is required",
+		},
+		{
+			// Roles are validated in alphabetical order, whatever the
+			// order of the file.
+			name: "several errors",
+			yml:  "clusters: [{name: a, resourcemanagers: [{}], datanodes: [{}], namenodes: [{}]}]\n",
+			err:  "cluster a: datanode 0: url is required",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for i := 0; i < 10; i++ {
+				_, err := load(t, tc.yml)
+				if err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+					t.Fatalf("Load() = %v, want an error starting with %q", err, tc.err)
+				}
+			}
+		})
+	}
+}
+
+const testConfig = `
+global:
+  timeout: 5s
+  user_name: hdfs
+  labels:
+    env: prod
+    dc: east
+clusters:
+  - name: a
+    kerberos:
+      principal: hadoop@EXAMPLE.COM
+      keytab_file: /etc/hadoop.keytab
+    top_users: 5
+    labels:
+      dc: west
+    namenodes:
+      - url: http://nn1:9870/jmx
+        nameservice: ns1
+      - name: nn2
+        url: http://nn2:9870/jmx
+        nameservice: ns1
+        timeout: 1s
+        basic_auth:
+          username: admin
+        labels:
+          rack: r2
+    datanodes:
+      - url: http://dn1:9864/jmx
+        collectors: [rules]
+        labels:
+          cluster: other
+  - name: b
+    namenodes:
+      - url: http://nn:9870/jmx
+`
+
+func TestTargets(t *testing.T) {
+	c, err := load(t, testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kerberos := &httpclient.Kerberos{Principal: "hadoop@EXAMPLE.COM", KeytabFile: "/etc/hadoop.keytab"}
+	for _, tc := range []struct {
+		role string
+		want []Target
+	}{
+		{
+			role: "namenode",
+			want: []Target{
+				{
+					// Cluster settings override global ones, and labels
+					// are merged.
+					Name: "nn1:9870", URL: "http://nn1:9870/jmx", NameService: "ns1", Cluster: "a", Role: "namenode",
+					Settings: Settings{
+						Config:   httpclient.Config{Timeout: 5 * time.Second, Kerberos: kerberos, UserName: "hdfs"},
+						TopUsers: 5,
+						Labels:   map[string]string{"env": "prod", "dc": "west", "cluster": "a", "target": "nn1:9870", "rack": ""},
+					},
+				},
+				{
+					// Basic authentication replaces Kerberos.
+					Name: "nn2", URL: "http://nn2:9870/jmx", NameService: "ns1", Cluster: "a", Role: "namenode",
+					Settings: Settings{
+						Config:   httpclient.Config{Timeout: time.Second, BasicAuth: &httpclient.BasicAuth{Username: "admin"}, UserName: "hdfs"},
+						TopUsers: 5,
+						Labels:   map[string]string{"env": "prod", "dc": "west", "cluster": "a", "target": "nn2", "rack": "r2"},
+					},
+				},
+				{
+					Name: "nn:9870", URL: "http://nn:9870/jmx", Cluster: "b", Role: "namenode",
+					Settings: Settings{
+						Config: httpclient.Config{Timeout: 5 * time.Second, UserName: "hdfs"},
+						Labels: map[string]string{"env": "prod", "dc": "east", "cluster": "b", "target": "nn:9870", "rack": ""},
+					},
+				},
+			},
+		},
+		{
+			role: "datanode",
+			want: []Target{
+				{
+					// The cluster label can be overridden.
+					Name: "dn1:9864", URL: "http://dn1:9864/jmx", Cluster: "a", Role: "datanode",
+					Settings: Settings{
+						Config:     httpclient.Config{Timeout: 5 * time.Second, Kerberos: kerberos, UserName: "hdfs"},
+						Collectors: []string{"rules"},
+						TopUsers:   5,
+						Labels:     map[string]string{"env": "prod", "dc": "west", "cluster": "other", "target": "dn1:9864"},
+					},
+				},
+			},
+		},
+		{role: "journalnode"},
+	} {
+		t.Run(tc.role, func(t *testing.T) {
+			got := c.Targets(tc.role)
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %d targets, want %d", len(got), len(tc.want))
+			}
+			for i := range got {
+				if !reflect.DeepEqual(got[i], tc.want[i]) {
+					t.Errorf("target %d = %+v, want %+v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	c, err := load(t, "clusters: [{name: a, datanodes: [{url: 'http://dn1:9864/jmx'}]}]\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Targets("datanode")[0].Timeout; got != DefaultTimeout {
+		t.Errorf("timeout = %v, want %v", got, DefaultTimeout)
+	}
+}
+
+func TestNameServices(t *testing.T) {
+	c, err := load(t, testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, ns := range c.NameServices() {
+		var names []string
+		for _, nn := range ns.NameNodes {
+			names = append(names, nn.Name)
+		}
+		got = append(got, ns.Cluster+"/"+ns.Name+": "+strings.Join(names, ","))
+	}
+	want := []string{"a/ns1: nn1:9870,nn2", "b/: nn:9870"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NameServices() = %q, want %q", got, want)
+	}
+	// The cluster labels, without the target's.
+	labels := c.NameServices()[0].Labels
+	wantLabels := map[string]string{"env": "prod", "dc": "west", "cluster": "a", "nameservice": "ns1"}
+	if !reflect.DeepEqual(labels, wantLabels) {
+		t.Errorf("labels = %v, want %v", labels, wantLabels)
+	}
+}
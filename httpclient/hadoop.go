@@ -0,0 +1,41 @@
+package httpclient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// delegationTokenHeader is the header Hadoop's
+// DelegationTokenAuthenticationHandler reads tokens from.
+const delegationTokenHeader = "X-Hadoop-Delegation-Token"
+
+// hadoopAuthRoundTripper authenticates requests the way Hadoop's
+// AuthenticationFilter expects for simple (pseudo) authentication and
+// delegation tokens.
+type hadoopAuthRoundTripper struct {
+	// userName is sent as the user.name query parameter.
+	userName string
+	// tokenFile holds the encoded delegation token. It is read on every
+	// request so that the token can be renewed by another process.
+	tokenFile string
+	rt        http.RoundTripper
+}
+
+func (h *hadoopAuthRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if h.userName != "" {
+		q := req.URL.Query()
+		q.Set("user.name", h.userName)
+		req.URL.RawQuery = q.Encode()
+	}
+	if h.tokenFile != "" {
+		data, err := ioutil.ReadFile(h.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("delegation token: %v", err)
+		}
+		req.Header.Set(delegationTokenHeader, strings.TrimSpace(string(data)))
+	}
+	return h.rt.RoundTrip(req)
+}
@@ -0,0 +1,139 @@
+package httpclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// authRequest is what an authenticationFilter stub saw of a request.
+type authRequest struct {
+	userName string
+	token    string
+	cookie   string
+	query    string
+}
+
+// authenticationFilter mimics Hadoop's AuthenticationFilter: requests are
+// authenticated by their hadoop.auth cookie, or else by user.name or a
+// delegation token, in which case the cookie is set.
+func authenticationFilter(requests *[]authRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := authRequest{
+			userName: r.URL.Query().Get("user.name"),
+			token:    r.Header.Get(delegationTokenHeader),
+			query:    r.URL.Query().Get("qry"),
+		}
+		if c, err := r.Cookie("hadoop.auth"); err == nil {
+			req.cookie = c.Value
+		}
+		*requests = append(*requests, req)
+		switch {
+		case req.cookie != "":
+		case req.userName != "" || req.token != "":
+			http.SetCookie(w, &http.Cookie{Name: "hadoop.auth", Value: "u=" + req.userName + "&t=simple", Path: "/"})
+		default:
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"beans":[]}`))
+	}))
+}
+
+func TestHadoopAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hadoop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("token-1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		cfg  Config
+		// want are the requests seen by the endpoint for three GETs,
+		// the token file being rewritten before the last one.
+		want []authRequest
+	}{
+		{
+			name: "user.name",
+			cfg:  Config{UserName: "hdfs"},
+			want: []authRequest{
+				{userName: "hdfs", query: "Hadoop:*"},
+				{userName: "hdfs", cookie: "u=hdfs&t=simple", query: "Hadoop:*"},
+				{userName: "hdfs", cookie: "u=hdfs&t=simple", query: "Hadoop:*"},
+			},
+		},
+		{
+			// The file is read for every request.
+			name: "delegation token",
+			cfg:  Config{DelegationTokenFile: tokenFile},
+			want: []authRequest{
+				{token: "token-1", query: "Hadoop:*"},
+				{token: "token-1", cookie: "u=&t=simple", query: "Hadoop:*"},
+				{token: "token-2", cookie: "u=&t=simple", query: "Hadoop:*"},
+			},
+		},
+		{
+			name: "none",
+			cfg:  Config{},
+			want: []authRequest{{query: "Hadoop:*"}, {query: "Hadoop:*"}, {query: "Hadoop:*"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := ioutil.WriteFile(tokenFile, []byte("token-1\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			var requests []authRequest
+			srv := authenticationFilter(&requests)
+			defer srv.Close()
+			client, err := New(tc.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 3; i++ {
+				if i == 2 {
+					if err := ioutil.WriteFile(tokenFile, []byte("token-2\n"), 0600); err != nil {
+						t.Fatal(err)
+					}
+				}
+				resp, err := client.Get(srv.URL + "/jmx?qry=Hadoop:*")
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+			}
+			if len(requests) != len(tc.want) {
+				t.Fatalf("got %d requests, want %d", len(requests), len(tc.want))
+			}
+			for i, r := range requests {
+				if r != tc.want[i] {
+					t.Errorf("request %d = %+v, want %+v", i, r, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDelegationTokenFileMissing(t *testing.T) {
+	var requests []authRequest
+	srv := authenticationFilter(&requests)
+	defer srv.Close()
+	client, err := New(Config{DelegationTokenFile: filepath.Join(os.TempDir(), "missing-delegation-token")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get(srv.URL + "/jmx"); err == nil || !strings.Contains(err.Error(), "delegation token: ") {
+		t.Errorf("Get() = %v, want a delegation token error", err)
+	}
+	if len(requests) != 0 {
+		t.Errorf("the request was sent without its token")
+	}
+}
@@ -0,0 +1,164 @@
+// Package httpclient builds the HTTP clients used to scrape Hadoop daemons.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"time"
+)
+
+// Config configures the client used for one target.
+type Config struct {
+	// Timeout bounds a whole request. No timeout is applied if it is zero.
+	Timeout   time.Duration `yaml:"timeout"`
+	BasicAuth *BasicAuth    `yaml:"basic_auth"`
+	TLSConfig *TLSConfig    `yaml:"tls_config"`
+	Kerberos  *Kerberos     `yaml:"kerberos"`
+	// UserName is sent as the user.name query parameter expected by
+	// endpoints with simple authentication.
+	UserName string `yaml:"user_name"`
+	// DelegationTokenFile holds a Hadoop delegation token, in its URL-safe
+	// encoding, sent with every request.
+	DelegationTokenFile string `yaml:"delegation_token_file"`
+}
+
+// BasicAuth holds HTTP basic authentication credentials.
+type BasicAuth struct {
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"`
+}
+
+// TLSConfig configures connections to HTTPS endpoints.
+type TLSConfig struct {
+	// CAFile is a PEM bundle of the CAs trusted to sign server certificates.
+	// The system roots are used if it is empty.
+	CAFile string `yaml:"ca_file"`
+	// CertFile and KeyFile are the client certificate and key presented to
+	// servers that require mutual TLS.
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ServerName overrides the name the server certificate is verified
+	// against.
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// Validate checks the configuration without reading any file.
+func (c *Config) Validate() error {
+	if c.Timeout < 0 {
+		return errors.New("timeout must not be negative")
+	}
+	if a := c.BasicAuth; a != nil {
+		if a.Username == "" {
+			return errors.New("basic_auth: username is required")
+		}
+		if a.Password != "" && a.PasswordFile != "" {
+			return errors.New("basic_auth: at most one of password and password_file may be set")
+		}
+	}
+	if k := c.Kerberos; k != nil {
+		if c.BasicAuth != nil {
+			return errors.New("at most one of basic_auth and kerberos may be set")
+		}
+		if err := k.validate(); err != nil {
+			return err
+		}
+	}
+	if t := c.TLSConfig; t != nil {
+		if (t.CertFile == "") != (t.KeyFile == "") {
+			return errors.New("tls_config: cert_file and key_file must be set together")
+		}
+	}
+	return nil
+}
+
+// New returns a client for cfg, reading the files it refers to. The client
+// keeps the cookies set by the server, such as the hadoop.auth cookie issued
+// once a request has been authenticated, so that later requests do not
+// authenticate again.
+func New(cfg Config) (*http.Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	tlsConfig, err := newTLSConfig(cfg.TLSConfig)
+	if err != nil {
+		return nil, err
+	}
+	var rt http.RoundTripper = &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: 10 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	if a := cfg.BasicAuth; a != nil {
+		password := a.Password
+		if a.PasswordFile != "" {
+			data, err := ioutil.ReadFile(a.PasswordFile)
+			if err != nil {
+				return nil, fmt.Errorf("basic_auth: %v", err)
+			}
+			password = strings.TrimSpace(string(data))
+		}
+		rt = &basicAuthRoundTripper{username: a.Username, password: password, rt: rt}
+	}
+	if cfg.UserName != "" || cfg.DelegationTokenFile != "" {
+		rt = &hadoopAuthRoundTripper{userName: cfg.UserName, tokenFile: cfg.DelegationTokenFile, rt: rt}
+	}
+	if k := cfg.Kerberos; k != nil {
+		if rt, err = newSPNEGORoundTripper(k, rt); err != nil {
+			return nil, err
+		}
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: rt, Jar: jar, Timeout: cfg.Timeout}, nil
+}
+
+func newTLSConfig(cfg *TLSConfig) (*tls.Config, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CAFile != "" {
+		data, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls_config: %v", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("tls_config: no certificate found in %s", cfg.CAFile)
+		}
+	}
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls_config: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+type basicAuthRoundTripper struct {
+	username string
+	password string
+	rt       http.RoundTripper
+}
+
+func (b *basicAuthRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.SetBasicAuth(b.username, b.password)
+	return b.rt.RoundTrip(req)
+}
@@ -0,0 +1,169 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	krbclient "github.com/jcmturner/gokrb5/v8/client"
+	krbconfig "github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+)
+
+// DefaultKrb5Conf is the Kerberos configuration read when Kerberos.Krb5Conf
+// is empty.
+const DefaultKrb5Conf = "/etc/krb5.conf"
+
+// Kerberos configures SPNEGO authentication, as required by Hadoop web
+// endpoints with hadoop.http.authentication.type=kerberos.
+type Kerberos struct {
+	// Principal is the client principal, as user@REALM or
+	// user/host@REALM.
+	Principal string `yaml:"principal"`
+	// KeytabFile holds the principal's keys.
+	KeytabFile string `yaml:"keytab_file"`
+	// Krb5Conf is the path to krb5.conf. It defaults to DefaultKrb5Conf.
+	Krb5Conf string `yaml:"krb5_conf"`
+	// SPN is the service principal of the endpoint. It defaults to
+	// HTTP/<canonical host name of the URL>.
+	SPN string `yaml:"spn"`
+}
+
+func (k *Kerberos) validate() error {
+	if k.Principal == "" {
+		return errors.New("kerberos: principal is required")
+	}
+	if i := strings.LastIndex(k.Principal, "@"); i <= 0 || i == len(k.Principal)-1 {
+		return fmt.Errorf("kerberos: principal %q must have the form user@REALM", k.Principal)
+	}
+	if k.KeytabFile == "" {
+		return errors.New("kerberos: keytab_file is required")
+	}
+	return nil
+}
+
+// kerberosClients caches the clients of the current configuration by
+// principal, keytab and krb5.conf. A client logs in once and renews its
+// tickets in the background, so the targets of a configuration share it.
+// Each configuration gets its own clients, see BeginKerberosGeneration.
+var kerberosClients = struct {
+	sync.Mutex
+	m map[Kerberos]*krbclient.Client
+}{m: map[Kerberos]*krbclient.Client{}}
+
+// BeginKerberosGeneration is called before a configuration is loaded. The
+// clients created from then on read their keytab and krb5.conf again, so
+// that rotated keytabs and edited krb5.conf files are picked up. The
+// returned function must be called once the configuration is in use, with
+// ok set, or failed to load: it destroys the clients of the configuration
+// that is no longer used, stopping their ticket renewal, and restores those
+// of the previous one on failure.
+func BeginKerberosGeneration() func(ok bool) {
+	kerberosClients.Lock()
+	prev := kerberosClients.m
+	kerberosClients.m = map[Kerberos]*krbclient.Client{}
+	kerberosClients.Unlock()
+	return func(ok bool) {
+		kerberosClients.Lock()
+		defer kerberosClients.Unlock()
+		unused := prev
+		if !ok {
+			unused = kerberosClients.m
+			kerberosClients.m = prev
+		}
+		for _, c := range unused {
+			c.Destroy()
+		}
+	}
+}
+
+// kerberosClient returns the client logging in as k.Principal. It logs in
+// lazily, when the first ticket is requested.
+func kerberosClient(k Kerberos) (*krbclient.Client, error) {
+	k.SPN = ""
+	if k.Krb5Conf == "" {
+		k.Krb5Conf = DefaultKrb5Conf
+	}
+	kerberosClients.Lock()
+	defer kerberosClients.Unlock()
+	if c, ok := kerberosClients.m[k]; ok {
+		return c, nil
+	}
+	conf, err := krbconfig.Load(k.Krb5Conf)
+	if err != nil {
+		return nil, fmt.Errorf("kerberos: %s: %v", k.Krb5Conf, err)
+	}
+	kt, err := keytab.Load(k.KeytabFile)
+	if err != nil {
+		return nil, fmt.Errorf("kerberos: %s: %v", k.KeytabFile, err)
+	}
+	i := strings.LastIndex(k.Principal, "@")
+	c := krbclient.NewWithKeytab(k.Principal[:i], k.Principal[i+1:], kt, conf, krbclient.DisablePAFXFAST(true))
+	kerberosClients.m[k] = c
+	return c, nil
+}
+
+// spnegoRoundTripper answers Negotiate challenges. A request is first sent
+// as is; if the server replies 401 with a Negotiate challenge, the request
+// is sent again with the token returned by negotiate.
+type spnegoRoundTripper struct {
+	// negotiate sets the Authorization header of req.
+	negotiate func(req *http.Request) error
+	rt        http.RoundTripper
+}
+
+func newSPNEGORoundTripper(k *Kerberos, rt http.RoundTripper) (*spnegoRoundTripper, error) {
+	c, err := kerberosClient(*k)
+	if err != nil {
+		return nil, err
+	}
+	spn := k.SPN
+	return &spnegoRoundTripper{
+		negotiate: func(req *http.Request) error {
+			return spnego.SetSPNEGOHeader(c, req, spn)
+		},
+		rt: rt,
+	}, nil
+}
+
+func (s *spnegoRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && req.GetBody == nil {
+		// The body could not be sent again.
+		return s.rt.RoundTrip(req)
+	}
+	resp, err := s.rt.RoundTrip(req)
+	if err != nil || !negotiateChallenge(resp) {
+		return resp, err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	req = req.Clone(req.Context())
+	if req.GetBody != nil {
+		if req.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.negotiate(req); err != nil {
+		return nil, fmt.Errorf("kerberos: %v", err)
+	}
+	return s.rt.RoundTrip(req)
+}
+
+// negotiateChallenge reports whether resp asks for SPNEGO authentication.
+func negotiateChallenge(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+	for _, v := range resp.Header["Www-Authenticate"] {
+		if v == "Negotiate" || strings.HasPrefix(v, "Negotiate ") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,397 @@
+package httpclient
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/goidentity/v6"
+	"github.com/jcmturner/gokrb5/v8/crypto"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
+	"github.com/jcmturner/gokrb5/v8/iana/msgtype"
+	"github.com/jcmturner/gokrb5/v8/iana/patype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+const (
+	testRealm = "EXAMPLE.COM"
+	testSPN   = "HTTP/localhost"
+)
+
+// testKDC is a KDC for the EXAMPLE.COM realm serving TCP on localhost. It
+// issues tickets to any principal of its keytab without pre-authentication.
+type testKDC struct {
+	t  *testing.T
+	kt *keytab.Keytab
+	ln net.Listener
+}
+
+// newTestKDC starts a KDC knowing the principals, by name, of passwords
+// besides krbtgt.
+func newTestKDC(t *testing.T, passwords map[string]string) *testKDC {
+	kt := keytab.New()
+	passwords["krbtgt/"+testRealm] = "krbtgt-secret"
+	for name, password := range passwords {
+		if err := kt.AddEntry(name, testRealm, password, time.Now(), 1, etypeID.AES256_CTS_HMAC_SHA1_96); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := &testKDC{t: t, kt: kt, ln: ln}
+	go k.serve()
+	return k
+}
+
+func (k *testKDC) Close() {
+	k.ln.Close()
+}
+
+func (k *testKDC) serve() {
+	for {
+		conn, err := k.ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			if err := k.handle(conn); err != nil {
+				k.t.Errorf("KDC: %v", err)
+			}
+		}()
+	}
+}
+
+// handle answers one request, framed as in RFC 4120 7.2.2.
+func (k *testKDC) handle(conn net.Conn) error {
+	var n uint32
+	if err := binary.Read(conn, binary.BigEndian, &n); err != nil {
+		return err
+	}
+	req := make([]byte, n)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return err
+	}
+	var (
+		resp []byte
+		err  error
+	)
+	var as messages.ASReq
+	if as.Unmarshal(req) == nil {
+		resp, err = k.asRep(as)
+	} else {
+		var tgs messages.TGSReq
+		if err := tgs.Unmarshal(req); err != nil {
+			return err
+		}
+		resp, err = k.tgsRep(tgs)
+	}
+	if err != nil {
+		return err
+	}
+	if err := binary.Write(conn, binary.BigEndian, uint32(len(resp))); err != nil {
+		return err
+	}
+	_, err = conn.Write(resp)
+	return err
+}
+
+// reply returns the KDC_REP fields of a ticket for sname issued to cname,
+// with the encrypted part encrypted with key for usage.
+func (k *testKDC) reply(msgType int, cname, sname types.PrincipalName, nonce int, key types.EncryptionKey, usage uint32) (messages.KDCRepFields, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	end := now.Add(time.Hour)
+	flags := types.NewKrbFlags()
+	tkt, sessionKey, err := messages.NewTicket(cname, testRealm, sname, testRealm, flags, k.kt, etypeID.AES256_CTS_HMAC_SHA1_96, 1, now, now, end, end)
+	if err != nil {
+		return messages.KDCRepFields{}, err
+	}
+	part := messages.EncKDCRepPart{
+		Key:       sessionKey,
+		LastReqs:  []messages.LastReq{},
+		Nonce:     nonce,
+		Flags:     flags,
+		AuthTime:  now,
+		StartTime: now,
+		EndTime:   end,
+		RenewTill: end,
+		SRealm:    testRealm,
+		SName:     sname,
+	}
+	b, err := part.Marshal()
+	if err != nil {
+		return messages.KDCRepFields{}, err
+	}
+	enc, err := crypto.GetEncryptedData(b, key, usage, 1)
+	if err != nil {
+		return messages.KDCRepFields{}, err
+	}
+	return messages.KDCRepFields{
+		PVNO:    5,
+		MsgType: msgType,
+		CRealm:  testRealm,
+		CName:   cname,
+		Ticket:  tkt,
+		EncPart: enc,
+	}, nil
+}
+
+// asRep issues a TGT, encrypted with the client's key.
+func (k *testKDC) asRep(req messages.ASReq) ([]byte, error) {
+	key, _, err := k.kt.GetEncryptionKey(req.ReqBody.CName, testRealm, 0, etypeID.AES256_CTS_HMAC_SHA1_96)
+	if err != nil {
+		return nil, err
+	}
+	rep, err := k.reply(msgtype.KRB_AS_REP, req.ReqBody.CName, req.ReqBody.SName, req.ReqBody.Nonce, key, keyusage.AS_REP_ENCPART)
+	if err != nil {
+		return nil, err
+	}
+	return (&messages.ASRep{KDCRepFields: rep}).Marshal()
+}
+
+// tgsRep issues a service ticket, encrypted with the session key of the
+// TGT presented in the request.
+func (k *testKDC) tgsRep(req messages.TGSReq) ([]byte, error) {
+	for _, pa := range req.PAData {
+		if pa.PADataType != patype.PA_TGS_REQ {
+			continue
+		}
+		var ap messages.APReq
+		if err := ap.Unmarshal(pa.PADataValue); err != nil {
+			return nil, err
+		}
+		if err := ap.Ticket.DecryptEncPart(k.kt, nil); err != nil {
+			return nil, err
+		}
+		tgt := ap.Ticket.DecryptedEncPart
+		rep, err := k.reply(msgtype.KRB_TGS_REP, tgt.CName, req.ReqBody.SName, req.ReqBody.Nonce, tgt.Key, keyusage.TGS_REP_ENCPART_SESSION_KEY)
+		if err != nil {
+			return nil, err
+		}
+		return (&messages.TGSRep{KDCRepFields: rep}).Marshal()
+	}
+	return nil, fmt.Errorf("TGS_REQ without a TGT")
+}
+
+// writeKeytab writes a keytab holding the key of principal derived from
+// password.
+func writeKeytab(t *testing.T, path, principal, password string) {
+	kt := keytab.New()
+	if err := kt.AddEntry(principal, testRealm, password, time.Now(), 1, etypeID.AES256_CTS_HMAC_SHA1_96); err != nil {
+		t.Fatal(err)
+	}
+	b, err := kt.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeKrb5Conf(t *testing.T, path, kdc string) {
+	conf := `[libdefaults]
+  default_realm = ` + testRealm + `
+  dns_lookup_kdc = false
+  dns_lookup_realm = false
+  udp_preference_limit = 1
+  default_tkt_enctypes = aes256-cts-hmac-sha1-96
+  default_tgs_enctypes = aes256-cts-hmac-sha1-96
+  permitted_enctypes = aes256-cts-hmac-sha1-96
+
+[realms]
+  ` + testRealm + ` = {
+    kdc = ` + kdc + `
+  }
+`
+	if err := ioutil.WriteFile(path, []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// kerberosEndpoint is a web endpoint authenticating requests with SPNEGO
+// as testSPN, replying with the name of the authenticated user.
+func kerberosEndpoint(t *testing.T, password string) *httptest.Server {
+	kt := keytab.New()
+	if err := kt.AddEntry(testSPN, testRealm, password, time.Now(), 1, etypeID.AES256_CTS_HMAC_SHA1_96); err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(spnego.SPNEGOKRB5Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := goidentity.FromHTTPRequestContext(r)
+		if id == nil {
+			http.Error(w, "no identity", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, id.UserName()+"@"+id.Domain())
+	}), kt))
+}
+
+// kerberosRealm starts a KDC knowing the hadoop principal and an endpoint
+// authenticating with it, and writes the krb5.conf of the realm in a
+// temporary directory. cleanup stops them and removes the directory.
+func kerberosRealm(t *testing.T) (srv *httptest.Server, dir, krb5Conf string, cleanup func()) {
+	kdc := newTestKDC(t, map[string]string{"hadoop": "hadoop-secret", testSPN: "http-secret"})
+	srv = kerberosEndpoint(t, "http-secret")
+	dir, err := ioutil.TempDir("", "kerberos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	krb5Conf = filepath.Join(dir, "krb5.conf")
+	writeKrb5Conf(t, krb5Conf, kdc.ln.Addr().String())
+	return srv, dir, krb5Conf, func() {
+		kdc.Close()
+		srv.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// get requests the endpoint with client and returns the user it was
+// authenticated as.
+func get(client *http.Client, srv *httptest.Server) (string, error) {
+	resp, err := client.Get(srv.URL + "/jmx")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return string(body), nil
+}
+
+func TestKerberos(t *testing.T) {
+	srv, dir, krb5Conf, cleanup := kerberosRealm(t)
+	defer cleanup()
+	writeKeytab(t, filepath.Join(dir, "hadoop.keytab"), "hadoop", "hadoop-secret")
+	writeKeytab(t, filepath.Join(dir, "stale.keytab"), "hadoop", "old-secret")
+
+	for _, tc := range []struct {
+		name     string
+		kerberos Kerberos
+		// newErr is the expected prefix of the error of New.
+		newErr string
+		// getFails is set if the client cannot authenticate.
+		getFails bool
+	}{
+		{
+			name:     "ok",
+			kerberos: Kerberos{Principal: "hadoop@" + testRealm, KeytabFile: filepath.Join(dir, "hadoop.keytab"), Krb5Conf: krb5Conf, SPN: testSPN},
+		},
+		{
+			name:     "stale keytab",
+			kerberos: Kerberos{Principal: "hadoop@" + testRealm, KeytabFile: filepath.Join(dir, "stale.keytab"), Krb5Conf: krb5Conf, SPN: testSPN},
+			getFails: true,
+		},
+		{
+			name:     "missing keytab",
+			kerberos: Kerberos{Principal: "hadoop@" + testRealm, KeytabFile: filepath.Join(dir, "missing.keytab"), Krb5Conf: krb5Conf},
+			newErr:   "kerberos: " + filepath.Join(dir, "missing.keytab"),
+		},
+		{
+			name:     "missing krb5.conf",
+			kerberos: Kerberos{Principal: "hadoop@" + testRealm, KeytabFile: filepath.Join(dir, "hadoop.keytab"), Krb5Conf: filepath.Join(dir, "missing.conf")},
+			newErr:   "kerberos: " + filepath.Join(dir, "missing.conf"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			kerberos := tc.kerberos
+			client, err := New(Config{Kerberos: &kerberos, Timeout: 10 * time.Second})
+			if tc.newErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tc.newErr) {
+					t.Errorf("New() = %v, want an error starting with %q", err, tc.newErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() = %v", err)
+			}
+			user, err := get(client, srv)
+			if tc.getFails {
+				if err == nil {
+					t.Fatal("the request succeeded")
+				}
+				if !strings.Contains(err.Error(), "kerberos: ") {
+					t.Errorf("err = %v, want a kerberos error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user != "hadoop@"+testRealm {
+				t.Errorf("authenticated as %q, want %q", user, "hadoop@"+testRealm)
+			}
+		})
+	}
+}
+
+func TestKerberosGeneration(t *testing.T) {
+	srv, dir, krb5Conf, cleanup := kerberosRealm(t)
+	defer cleanup()
+	keytabFile := filepath.Join(dir, "hadoop.keytab")
+	cfg := Config{
+		Kerberos: &Kerberos{Principal: "hadoop@" + testRealm, KeytabFile: keytabFile, Krb5Conf: krb5Conf, SPN: testSPN},
+		Timeout:  10 * time.Second,
+	}
+	// load creates a client in a new generation, and ends it with ok.
+	load := func(ok bool) *http.Client {
+		t.Helper()
+		end := BeginKerberosGeneration()
+		defer end(ok)
+		client, err := New(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+
+	writeKeytab(t, keytabFile, "hadoop", "old-secret")
+	stale := load(true)
+	if _, err := get(stale, srv); err == nil {
+		t.Fatal("authenticated with a stale keytab")
+	}
+
+	// The keytab is rotated; it is read again by the next generation only.
+	writeKeytab(t, keytabFile, "hadoop", "hadoop-secret")
+	client, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := get(client, srv); err == nil {
+		t.Fatal("the keytab was read again within a generation")
+	}
+	current := load(true)
+	if _, err := get(current, srv); err != nil {
+		t.Fatalf("after the keytab was rotated: %v", err)
+	}
+
+	// A failed generation leaves the clients of the previous one in use.
+	writeKeytab(t, keytabFile, "hadoop", "old-secret")
+	load(false)
+	client, err = New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := get(client, srv); err != nil {
+		t.Errorf("after a failed generation: %v", err)
+	}
+}
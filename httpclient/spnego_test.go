@@ -0,0 +1,154 @@
+package httpclient
+
+import (
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testToken = "test-spnego-token"
+
+// negotiateStub mimics the AuthenticationFilter of a Hadoop web endpoint
+// with Kerberos authentication: requests without a valid Negotiate token are
+// challenged.
+func negotiateStub(t *testing.T, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		auth := r.Header.Get("Authorization")
+		if auth == "" {
+			w.Header().Set("WWW-Authenticate", "Negotiate")
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		if !strings.HasPrefix(auth, "Negotiate ") {
+			t.Errorf("Authorization = %q, want a Negotiate token", auth)
+			http.Error(w, "unsupported scheme", http.StatusUnauthorized)
+			return
+		}
+		token, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, "Negotiate "))
+		if err != nil || string(token) != testToken {
+			http.Error(w, "invalid token", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", "Negotiate oRQwEqADCgEAoQsGCSqGSIb3EgECAg==")
+		w.Write([]byte(`{"beans":[]}`))
+	}))
+}
+
+func testNegotiate(calls *int, token string) func(*http.Request) error {
+	return func(req *http.Request) error {
+		*calls++
+		req.Header.Set("Authorization", "Negotiate "+base64.StdEncoding.EncodeToString([]byte(token)))
+		return nil
+	}
+}
+
+func TestSPNEGOHandshake(t *testing.T) {
+	var requests, calls int
+	srv := negotiateStub(t, &requests)
+	defer srv.Close()
+
+	client := &http.Client{Transport: &spnegoRoundTripper{
+		negotiate: testNegotiate(&calls, testToken),
+		rt:        http.DefaultTransport,
+	}}
+	req, _ := http.NewRequest("GET", srv.URL+"/jmx", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body %q", resp.StatusCode, body)
+	}
+	if string(body) != `{"beans":[]}` {
+		t.Errorf("body = %q", body)
+	}
+	if requests != 2 || calls != 1 {
+		t.Errorf("got %d requests and %d tokens, want 2 and 1", requests, calls)
+	}
+	if req.Header.Get("Authorization") != "" {
+		t.Error("the caller's request was modified")
+	}
+}
+
+func TestSPNEGORejectedToken(t *testing.T) {
+	var requests, calls int
+	srv := negotiateStub(t, &requests)
+	defer srv.Close()
+
+	client := &http.Client{Transport: &spnegoRoundTripper{
+		negotiate: testNegotiate(&calls, "wrong"),
+		rt:        http.DefaultTransport,
+	}}
+	resp, err := client.Get(srv.URL + "/jmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", resp.StatusCode)
+	}
+	if calls != 1 {
+		t.Errorf("got %d tokens, want 1", calls)
+	}
+}
+
+func TestSPNEGONoChallenge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"beans":[]}`))
+	}))
+	defer srv.Close()
+
+	var calls int
+	client := &http.Client{Transport: &spnegoRoundTripper{
+		negotiate: testNegotiate(&calls, testToken),
+		rt:        http.DefaultTransport,
+	}}
+	resp, err := client.Get(srv.URL + "/jmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls != 0 {
+		t.Errorf("status = %d with %d tokens, want 200 without any", resp.StatusCode, calls)
+	}
+}
+
+func TestSPNEGONegotiateError(t *testing.T) {
+	var requests int
+	srv := negotiateStub(t, &requests)
+	defer srv.Close()
+
+	client := &http.Client{Transport: &spnegoRoundTripper{
+		negotiate: func(*http.Request) error { return errors.New("no TGT") },
+		rt:        http.DefaultTransport,
+	}}
+	if _, err := client.Get(srv.URL + "/jmx"); err == nil || !strings.Contains(err.Error(), "no TGT") {
+		t.Errorf("err = %v, want the negotiation error", err)
+	}
+}
+
+func TestKerberosValidate(t *testing.T) {
+	for _, c := range []struct {
+		cfg Config
+		ok  bool
+	}{
+		{Config{Kerberos: &Kerberos{Principal: "hadoop@EXAMPLE.COM", KeytabFile: "/etc/hadoop.keytab"}}, true},
+		{Config{Kerberos: &Kerberos{Principal: "hadoop", KeytabFile: "/etc/hadoop.keytab"}}, false},
+		{Config{Kerberos: &Kerberos{Principal: "hadoop@EXAMPLE.COM"}}, false},
+		{Config{
+			Kerberos:  &Kerberos{Principal: "hadoop@EXAMPLE.COM", KeytabFile: "/etc/hadoop.keytab"},
+			BasicAuth: &BasicAuth{Username: "hadoop"},
+		}, false},
+	} {
+		if err := c.cfg.Validate(); (err == nil) != c.ok {
+			t.Errorf("Validate(%+v) = %v", c.cfg.Kerberos, err)
+		}
+	}
+}
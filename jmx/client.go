@@ -0,0 +1,80 @@
+package jmx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// FetchError records a failed request to a Hadoop endpoint.
+type FetchError struct {
+	URL    string
+	Reason string
+	Err    error
+}
+
+func (e *FetchError) Error() string {
+	return fmt.Sprintf("%s: %v", e.URL, e.Err)
+}
+
+// Client fetches JSON documents from Hadoop web endpoints.
+type Client struct {
+	HTTPClient *http.Client
+}
+
+// DefaultTimeout bounds the requests of DefaultClient, so that a daemon that
+// stopped answering does not block scrapes forever.
+const DefaultTimeout = 10 * time.Second
+
+// MaxBodySize bounds the size of the documents read by a Client. A large
+// NameNode's /jmx, listing every DataNode, is a few megabytes.
+const MaxBodySize = 64 << 20
+
+// DefaultClient is the Client used by Fetch and GetJSON.
+var DefaultClient = &Client{HTTPClient: &http.Client{Timeout: DefaultTimeout}}
+
+// Fetch retrieves and decodes the /jmx document at url.
+func (c *Client) Fetch(url string) (*Response, error) {
+	var r Response
+	if err := c.GetJSON(url, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GetJSON retrieves url and decodes its JSON body into v. It is also used for
+// the non-JMX REST APIs such as the ResourceManager's /ws/v1/cluster/metrics.
+func (c *Client) GetJSON(url string, v interface{}) error {
+	resp, err := c.HTTPClient.Get(url)
+	if err != nil {
+		return &FetchError{URL: url, Reason: ReasonRequest, Err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &FetchError{URL: url, Reason: ReasonStatus, Err: fmt.Errorf("unexpected status %s", resp.Status)}
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, MaxBodySize+1))
+	if err != nil {
+		return &FetchError{URL: url, Reason: ReasonRequest, Err: err}
+	}
+	if len(data) > MaxBodySize {
+		return &FetchError{URL: url, Reason: ReasonRequest, Err: fmt.Errorf("body larger than %d bytes", MaxBodySize)}
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return &FetchError{URL: url, Reason: ReasonDecode, Err: err}
+	}
+	return nil
+}
+
+// Fetch retrieves and decodes the /jmx document at url using DefaultClient.
+func Fetch(url string) (*Response, error) {
+	return DefaultClient.Fetch(url)
+}
+
+// GetJSON retrieves url and decodes its JSON body into v using DefaultClient.
+func GetJSON(url string, v interface{}) error {
+	return DefaultClient.GetJSON(url, v)
+}
@@ -0,0 +1,55 @@
+package jmx
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/jmx":
+			fmt.Fprint(w, `{"beans":[{"name":"a","x":1},{"x":2}]}`)
+		case "/html":
+			fmt.Fprint(w, `<html></html>`)
+		case "/slow":
+			time.Sleep(time.Second)
+			fmt.Fprint(w, `{"beans":[]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	client := &Client{HTTPClient: &http.Client{Timeout: 100 * time.Millisecond}}
+
+	resp, err := client.Fetch(srv.URL + "/jmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Beans) != 1 || resp.Beans[0].Name != "a" || len(resp.Skipped) != 1 {
+		t.Errorf("Fetch() = %+v", resp)
+	}
+
+	for _, tc := range []struct {
+		path   string
+		reason string
+	}{
+		{path: "/missing", reason: ReasonStatus},
+		{path: "/html", reason: ReasonDecode},
+		{path: "/slow", reason: ReasonRequest},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			_, err := client.Fetch(srv.URL + tc.path)
+			fe, ok := err.(*FetchError)
+			if !ok {
+				t.Fatalf("err = %v, want a FetchError", err)
+			}
+			if fe.URL != srv.URL+tc.path || fe.Reason != tc.reason {
+				t.Errorf("err = %+v, want reason %q", fe, tc.reason)
+			}
+		})
+	}
+}
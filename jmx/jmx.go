@@ -0,0 +1,234 @@
+// Package jmx decodes the JSON served by the Hadoop /jmx servlet.
+package jmx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+var (
+	// ErrMissing is returned when a bean has no attribute of the requested name.
+	ErrMissing = errors.New("attribute not found")
+	// ErrType is returned when an attribute holds a value of an unexpected type.
+	ErrType = errors.New("unexpected attribute type")
+)
+
+// AttributeError records a failed attribute lookup on a bean.
+type AttributeError struct {
+	Bean      string
+	Attribute string
+	Err       error
+}
+
+func (e *AttributeError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", e.Bean, e.Attribute, e.Err)
+}
+
+// Response is the decoded body of a /jmx request.
+type Response struct {
+	Beans []Bean `json:"beans"`
+	// Skipped holds a BeanError for each bean that could not be decoded.
+	// The other beans are kept, so that one malformed bean does not hide
+	// the whole response.
+	Skipped []error `json:"-"`
+}
+
+// BeanError records a bean of a response that could not be decoded.
+type BeanError struct {
+	// Index is the position of the bean in the response.
+	Index int
+	Err   error
+}
+
+func (e *BeanError) Error() string {
+	return fmt.Sprintf("bean %d: %v", e.Index, e.Err)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *Response) UnmarshalJSON(data []byte) error {
+	var body struct {
+		Beans []json.RawMessage `json:"beans"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return err
+	}
+	r.Beans, r.Skipped = nil, nil
+	for i, raw := range body.Beans {
+		var b Bean
+		if err := json.Unmarshal(raw, &b); err != nil {
+			r.Skipped = append(r.Skipped, &BeanError{Index: i, Err: err})
+			continue
+		}
+		r.Beans = append(r.Beans, b)
+	}
+	return nil
+}
+
+// Bean returns the bean with the given ObjectName.
+func (r *Response) Bean(name string) (Bean, bool) {
+	for _, b := range r.Beans {
+		if b.Name == name {
+			return b, true
+		}
+	}
+	return Bean{}, false
+}
+
+// Match returns every bean whose ObjectName matches re, in response order.
+func (r *Response) Match(re *regexp.Regexp) []Bean {
+	var beans []Bean
+	for _, b := range r.Beans {
+		if re.MatchString(b.Name) {
+			beans = append(beans, b)
+		}
+	}
+	return beans
+}
+
+// Bean is a single MBean: its ObjectName and its attributes. Composite
+// attributes such as java.lang:type=Memory's HeapMemoryUsage are kept as
+// nested maps and can be read with Composite.
+type Bean struct {
+	Name       string
+	Attributes map[string]interface{}
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *Bean) UnmarshalJSON(data []byte) error {
+	var attrs map[string]interface{}
+	if err := json.Unmarshal(data, &attrs); err != nil {
+		return err
+	}
+	name, ok := attrs["name"].(string)
+	if !ok {
+		return errors.New("bean without name")
+	}
+	delete(attrs, "name")
+	b.Name = name
+	b.Attributes = attrs
+	return nil
+}
+
+// Get returns the raw value of an attribute.
+func (b Bean) Get(attr string) (interface{}, error) {
+	v, ok := b.Attributes[attr]
+	if !ok {
+		return nil, &AttributeError{Bean: b.Name, Attribute: attr, Err: ErrMissing}
+	}
+	return v, nil
+}
+
+// Float returns a numeric attribute.
+func (b Bean) Float(attr string) (float64, error) {
+	v, err := b.Get(attr)
+	if err != nil {
+		return 0, err
+	}
+	f, ok := v.(float64)
+	if !ok {
+		return 0, &AttributeError{Bean: b.Name, Attribute: attr, Err: ErrType}
+	}
+	return f, nil
+}
+
+// String returns a string attribute.
+func (b Bean) String(attr string) (string, error) {
+	v, err := b.Get(attr)
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", &AttributeError{Bean: b.Name, Attribute: attr, Err: ErrType}
+	}
+	return s, nil
+}
+
+// Bool returns a boolean attribute.
+func (b Bean) Bool(attr string) (bool, error) {
+	v, err := b.Get(attr)
+	if err != nil {
+		return false, err
+	}
+	t, ok := v.(bool)
+	if !ok {
+		return false, &AttributeError{Bean: b.Name, Attribute: attr, Err: ErrType}
+	}
+	return t, nil
+}
+
+// JSON decodes a string attribute holding a JSON document, such as the
+// NameNodeInfo bean's LiveNodes, into v.
+func (b Bean) JSON(attr string, v interface{}) error {
+	s, err := b.String(attr)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(s), v); err != nil {
+		return &AttributeError{Bean: b.Name, Attribute: attr, Err: err}
+	}
+	return nil
+}
+
+// Decode decodes an attribute holding an array or composite value, such as
+// the SnapshotInfo bean's Snapshots, into v.
+func (b Bean) Decode(attr string, v interface{}) error {
+	raw, err := b.Get(attr)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(raw)
+	if err == nil {
+		err = json.Unmarshal(data, v)
+	}
+	if err != nil {
+		return &AttributeError{Bean: b.Name, Attribute: attr, Err: err}
+	}
+	return nil
+}
+
+// Composite returns a composite attribute as a bean carrying the same
+// ObjectName, so that its fields can be read with the same accessors.
+func (b Bean) Composite(attr string) (Bean, error) {
+	v, err := b.Get(attr)
+	if err != nil {
+		return Bean{}, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return Bean{}, &AttributeError{Bean: b.Name, Attribute: attr, Err: ErrType}
+	}
+	return Bean{Name: b.Name, Attributes: m}, nil
+}
+
+// Reasons returned by Reason, used to label scrape error counters.
+const (
+	ReasonRequest          = "request"
+	ReasonStatus           = "status"
+	ReasonDecode           = "decode"
+	ReasonMissingAttribute = "missing_attribute"
+	ReasonInvalidAttribute = "invalid_attribute"
+	ReasonUnknown          = "unknown"
+)
+
+// Reason classifies an error returned by this package, or by any package
+// whose errors have a Reason method.
+func Reason(err error) string {
+	if r, ok := err.(interface{ Reason() string }); ok {
+		return r.Reason()
+	}
+	switch e := err.(type) {
+	case *FetchError:
+		return e.Reason
+	case *BeanError:
+		return ReasonDecode
+	case *AttributeError:
+		if e.Err == ErrMissing {
+			return ReasonMissingAttribute
+		}
+		return ReasonInvalidAttribute
+	}
+	return ReasonUnknown
+}
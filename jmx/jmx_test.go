@@ -0,0 +1,193 @@
+package jmx
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestUnmarshalResponse(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		data  string
+		beans []Bean
+		// skipped are the indexes of the beans skipped.
+		skipped []int
+		err     bool
+	}{
+		{
+			name: "beans",
+			data: `{"beans":[{"name":"a","x":1,"s":"v"},{"name":"b","c":{"used":2}}]}`,
+			beans: []Bean{
+				{Name: "a", Attributes: map[string]interface{}{"x": 1.0, "s": "v"}},
+				{Name: "b", Attributes: map[string]interface{}{"c": map[string]interface{}{"used": 2.0}}},
+			},
+		},
+		{
+			name: "no beans",
+			data: `{"beans":[]}`,
+		},
+		{
+			name:    "bean without name",
+			data:    `{"beans":[{"x":1},{"name":"b","y":2}]}`,
+			beans:   []Bean{{Name: "b", Attributes: map[string]interface{}{"y": 2.0}}},
+			skipped: []int{0},
+		},
+		{
+			name:    "malformed beans",
+			data:    `{"beans":[{"name":1},"bean",{"name":"c"}]}`,
+			beans:   []Bean{{Name: "c", Attributes: map[string]interface{}{}}},
+			skipped: []int{0, 1},
+		},
+		{
+			name: "not a response",
+			data: `{"beans":{}}`,
+			err:  true,
+		},
+		{
+			name: "invalid JSON",
+			data: `{"beans":[`,
+			err:  true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var r Response
+			err := json.Unmarshal([]byte(tc.data), &r)
+			if tc.err {
+				if err == nil {
+					t.Fatal("no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(r.Beans, tc.beans) {
+				t.Errorf("beans = %v, want %v", r.Beans, tc.beans)
+			}
+			var skipped []int
+			for _, err := range r.Skipped {
+				be, ok := err.(*BeanError)
+				if !ok {
+					t.Fatalf("skipped %T, want *BeanError", err)
+				}
+				skipped = append(skipped, be.Index)
+			}
+			if !reflect.DeepEqual(skipped, tc.skipped) {
+				t.Errorf("skipped = %v, want %v", skipped, tc.skipped)
+			}
+		})
+	}
+}
+
+func TestResponseLookup(t *testing.T) {
+	r := &Response{Beans: []Bean{
+		{Name: "Hadoop:service=NameNode,name=RpcActivityForPort8020"},
+		{Name: "Hadoop:service=NameNode,name=FSNamesystem"},
+		{Name: "Hadoop:service=NameNode,name=RpcActivityForPort8021"},
+	}}
+	if b, ok := r.Bean("Hadoop:service=NameNode,name=FSNamesystem"); !ok || b.Name != "Hadoop:service=NameNode,name=FSNamesystem" {
+		t.Errorf("Bean() = %v, %v", b, ok)
+	}
+	if _, ok := r.Bean("Hadoop:service=NameNode,name=FSNamesystemState"); ok {
+		t.Error("Bean() found a missing bean")
+	}
+	var names []string
+	for _, b := range r.Match(regexp.MustCompile(`RpcActivityForPort\d+$`)) {
+		names = append(names, b.Name)
+	}
+	want := []string{"Hadoop:service=NameNode,name=RpcActivityForPort8020", "Hadoop:service=NameNode,name=RpcActivityForPort8021"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Match() = %v, want %v", names, want)
+	}
+}
+
+func TestAccessors(t *testing.T) {
+	var b Bean
+	if err := json.Unmarshal([]byte(`{
+		"name": "bean",
+		"num": 1.5,
+		"str": "s",
+		"bool": true,
+		"doc": "{\"a\":1}",
+		"arr": [{"a":2}],
+		"comp": {"used": 3}
+	}`), &b); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct{ A int }
+	var arr []struct{ A int }
+	for _, tc := range []struct {
+		name string
+		get  func() (interface{}, error)
+		want interface{}
+		// err is the expected error of the attribute lookup, if any.
+		err error
+	}{
+		{name: "Float", get: func() (interface{}, error) { return b.Float("num") }, want: 1.5},
+		{name: "Float type", get: func() (interface{}, error) { return b.Float("str") }, err: ErrType},
+		{name: "Float missing", get: func() (interface{}, error) { return b.Float("none") }, err: ErrMissing},
+		{name: "String", get: func() (interface{}, error) { return b.String("str") }, want: "s"},
+		{name: "String type", get: func() (interface{}, error) { return b.String("num") }, err: ErrType},
+		{name: "Bool", get: func() (interface{}, error) { return b.Bool("bool") }, want: true},
+		{name: "Bool type", get: func() (interface{}, error) { return b.Bool("str") }, err: ErrType},
+		{name: "JSON", get: func() (interface{}, error) { err := b.JSON("doc", &doc); return doc.A, err }, want: 1},
+		{name: "JSON type", get: func() (interface{}, error) { return nil, b.JSON("num", &doc) }, err: ErrType},
+		{name: "Decode", get: func() (interface{}, error) { err := b.Decode("arr", &arr); return len(arr), err }, want: 1},
+		{name: "Decode missing", get: func() (interface{}, error) { return nil, b.Decode("none", &arr) }, err: ErrMissing},
+		{
+			name: "Composite",
+			get: func() (interface{}, error) {
+				c, err := b.Composite("comp")
+				if err != nil {
+					return nil, err
+				}
+				return c.Float("used")
+			},
+			want: 3.0,
+		},
+		{name: "Composite type", get: func() (interface{}, error) { return b.Composite("num") }, err: ErrType},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.get()
+			if tc.err != nil {
+				ae, ok := err.(*AttributeError)
+				if !ok || ae.Err != tc.err || ae.Bean != "bean" {
+					t.Errorf("err = %#v, want an AttributeError of bean with %v", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+type reasonError struct{}
+
+func (reasonError) Error() string  { return "reason" }
+func (reasonError) Reason() string { return "custom" }
+
+func TestReason(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{err: &FetchError{Reason: ReasonStatus}, want: ReasonStatus},
+		{err: &BeanError{Err: errors.New("bean without name")}, want: ReasonDecode},
+		{err: &AttributeError{Err: ErrMissing}, want: ReasonMissingAttribute},
+		{err: &AttributeError{Err: ErrType}, want: ReasonInvalidAttribute},
+		{err: reasonError{}, want: "custom"},
+		{err: errors.New("other"), want: ReasonUnknown},
+	} {
+		if got := Reason(tc.err); got != tc.want {
+			t.Errorf("Reason(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
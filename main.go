@@ -0,0 +1,416 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/log"
+	"github.com/wyukawa/hadoop_exporter/collector"
+	"github.com/wyukawa/hadoop_exporter/config"
+	"github.com/wyukawa/hadoop_exporter/httpclient"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+	"github.com/wyukawa/hadoop_exporter/rules"
+	"github.com/wyukawa/hadoop_exporter/web"
+)
+
+// role describes the command-line interface of one collector.
+type role struct {
+	title         string
+	listenAddress string
+	// urlFlag is the flag holding the daemon's URL. Setting it explicitly
+	// overrides the role's targets in the configuration file.
+	urlFlag string
+	// flags registers the role's flags on fs. The returned function creates
+	// the collector once the flags have been parsed.
+	flags func(fs *flag.FlagSet) func(opts collector.Options) (*collector.Exporter, error)
+}
+
+var roles = map[string]role{
+	"namenode": {
+		title:         "NameNode",
+		listenAddress: ":9070",
+		urlFlag:       "namenode.jmx.url",
+		flags: func(fs *flag.FlagSet) func(collector.Options) (*collector.Exporter, error) {
+			url := fs.String("namenode.jmx.url", "http://localhost:50070/jmx", "Hadoop JMX URL.")
+			topUsers := fs.Int("namenode.top-users", collector.DefaultTopUsers, "Number of users exported per operation and RPC server by the top_users collector.")
+			return func(opts collector.Options) (*collector.Exporter, error) {
+				opts.TopUsers = *topUsers
+				return collector.New("namenode", *url, opts)
+			}
+		},
+	},
+	"datanode": {
+		title:         "DataNode",
+		listenAddress: ":9070",
+		urlFlag:       "datanode.jmx.url",
+		flags: func(fs *flag.FlagSet) func(collector.Options) (*collector.Exporter, error) {
+			url := fs.String("datanode.jmx.url", "http://localhost:50075/jmx", "Hadoop Datanode JMX URL.")
+			return func(opts collector.Options) (*collector.Exporter, error) {
+				return collector.New("datanode", *url, opts)
+			}
+		},
+	},
+	"journalnode": {
+		title:         "JournalNode",
+		listenAddress: ":9070",
+		urlFlag:       "journalnode.jmx.url",
+		flags: func(fs *flag.FlagSet) func(collector.Options) (*collector.Exporter, error) {
+			url := fs.String("journalnode.jmx.url", "http://localhost:8480/jmx", "Hadoop journalnode JMX URL.")
+			clusterName := fs.String("journalnode.cluster.name", "hadoop-cluster", "Hadoop Cluster Name")
+			return func(opts collector.Options) (*collector.Exporter, error) {
+				opts.ClusterName = *clusterName
+				return collector.New("journalnode", *url, opts)
+			}
+		},
+	},
+	"resourcemanager": {
+		title:         "ResourceManager",
+		listenAddress: ":9088",
+		urlFlag:       "resourcemanager.url",
+		flags: func(fs *flag.FlagSet) func(collector.Options) (*collector.Exporter, error) {
+			url := fs.String("resourcemanager.url", "http://localhost:8088", "Hadoop ResourceManager URL.")
+			return func(opts collector.Options) (*collector.Exporter, error) {
+				return collector.New("resourcemanager", *url, opts)
+			}
+		},
+	},
+	"nodemanager": {
+		title:         "NodeManager",
+		listenAddress: ":9070",
+		urlFlag:       "nodemanager.jmx.url",
+		flags: func(fs *flag.FlagSet) func(collector.Options) (*collector.Exporter, error) {
+			url := fs.String("nodemanager.jmx.url", "http://localhost:8042/jmx", "Hadoop NodeManager JMX URL.")
+			return func(opts collector.Options) (*collector.Exporter, error) {
+				return collector.New("nodemanager", *url, opts)
+			}
+		},
+	},
+	"jobhistoryserver": {
+		title:         "JobHistoryServer",
+		listenAddress: ":9070",
+		urlFlag:       "jobhistoryserver.jmx.url",
+		flags: func(fs *flag.FlagSet) func(collector.Options) (*collector.Exporter, error) {
+			url := fs.String("jobhistoryserver.jmx.url", "http://localhost:19888/jmx", "Hadoop JobHistoryServer JMX URL.")
+			return func(opts collector.Options) (*collector.Exporter, error) {
+				return collector.New("jobhistoryserver", *url, opts)
+			}
+		},
+	},
+}
+
+// autoFlags registers the flags of the auto command and returns the JMX URL
+// to detect the roles from.
+func autoFlags(fs *flag.FlagSet) *string {
+	return fs.String("jmx.url", "http://localhost:50070/jmx", "Hadoop JMX URL used to detect the roles to export.")
+}
+
+// clientFlags registers the flags configuring the client used for the
+// targets given by flags, detection and probes. The returned function creates
+// the client once the flags have been parsed, and the client for probes of
+// other targets, which does not send the user name and delegation token.
+func clientFlags(fs *flag.FlagSet) func() (client, probeClient *jmx.Client, err error) {
+	var tlsConfig httpclient.TLSConfig
+	fs.StringVar(&tlsConfig.CAFile, "tls.ca-file", "", "PEM file of the CAs trusted to sign the certificates of HTTPS endpoints. The system roots are used by default.")
+	fs.StringVar(&tlsConfig.CertFile, "tls.cert-file", "", "PEM client certificate presented to HTTPS endpoints requiring mutual TLS.")
+	fs.StringVar(&tlsConfig.KeyFile, "tls.key-file", "", "PEM key of -tls.cert-file.")
+	fs.StringVar(&tlsConfig.ServerName, "tls.server-name", "", "Name the certificates of HTTPS endpoints are verified against instead of the host of the URL.")
+	fs.BoolVar(&tlsConfig.InsecureSkipVerify, "tls.insecure-skip-verify", false, "Do not verify the certificates of HTTPS endpoints.")
+	cfg := httpclient.Config{Timeout: jmx.DefaultTimeout}
+	fs.StringVar(&cfg.UserName, "auth.user-name", "", "User name sent as the user.name parameter to endpoints with simple authentication.")
+	fs.StringVar(&cfg.DelegationTokenFile, "auth.delegation-token-file", "", "File holding a Hadoop delegation token sent with every request. It is read again for every request.")
+	newClient := func(cfg httpclient.Config) (*jmx.Client, error) {
+		if cfg.TLSConfig == nil && cfg.UserName == "" && cfg.DelegationTokenFile == "" {
+			return jmx.DefaultClient, nil
+		}
+		c, err := httpclient.New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &jmx.Client{HTTPClient: c}, nil
+	}
+	return func() (*jmx.Client, *jmx.Client, error) {
+		if tlsConfig != (httpclient.TLSConfig{}) {
+			cfg.TLSConfig = &tlsConfig
+		}
+		client, err := newClient(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cfg.UserName == "" && cfg.DelegationTokenFile == "" {
+			return client, client, nil
+		}
+		anonymous := cfg
+		anonymous.UserName, anonymous.DelegationTokenFile = "", ""
+		probeClient, err := newClient(anonymous)
+		if err != nil {
+			return nil, nil, err
+		}
+		return client, probeClient, nil
+	}
+}
+
+// detect creates a collector for every role served at the JMX URL.
+func detect(url string, ruleSets map[string]*rules.Set, client *jmx.Client) ([]*collector.Exporter, error) {
+	doc, err := collector.FetchDocument(url, client)
+	if err != nil {
+		return nil, err
+	}
+	return documentExporters(doc, ruleSets, client)
+}
+
+// documentExporters creates a collector for every role whose beans are in
+// the /jmx document.
+func documentExporters(doc *collector.Document, ruleSets map[string]*rules.Set, client *jmx.Client) ([]*collector.Exporter, error) {
+	targets := doc.Targets()
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%s: no known Hadoop service found", doc.URL)
+	}
+	var exporters []*collector.Exporter
+	for _, t := range targets {
+		exporter, err := collector.New(t.Role, t.URL, collector.Options{Rules: ruleSets[t.Role], Client: client})
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, exporter)
+	}
+	return exporters, nil
+}
+
+// configExporters creates a collector for every target of role in cfg.
+func configExporters(cfg *config.Config, role string, ruleSets map[string]*rules.Set) ([]*collector.Exporter, error) {
+	var exporters []*collector.Exporter
+	for _, t := range cfg.Targets(role) {
+		client, err := httpclient.New(t.Config)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %s %s %s: %v", t.Cluster, role, t.Name, err)
+		}
+		exporter, err := collector.New(role, t.URL, collector.Options{
+			Rules:       ruleSets[role],
+			ClusterName: t.Journal,
+			Client:      &jmx.Client{HTTPClient: client},
+			Labels:      t.Labels,
+			Collectors:  t.Collectors,
+			TopUsers:    t.TopUsers,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cluster %s %s %s: %v", t.Cluster, role, t.Name, err)
+		}
+		exporters = append(exporters, exporter)
+	}
+	return exporters, nil
+}
+
+// configHA creates a collector for the HA state of every nameservice in cfg.
+func configHA(cfg *config.Config) ([]*collector.HA, error) {
+	var collectors []*collector.HA
+	for _, ns := range cfg.NameServices() {
+		var namenodes []collector.HANameNode
+		for _, t := range ns.NameNodes {
+			client, err := httpclient.New(t.Config)
+			if err != nil {
+				return nil, fmt.Errorf("cluster %s namenode %s: %v", t.Cluster, t.Name, err)
+			}
+			namenodes = append(namenodes, collector.HANameNode{Name: t.Name, URL: t.URL, Client: &jmx.Client{HTTPClient: client}})
+		}
+		collectors = append(collectors, collector.NewHA(namenodes, ns.Labels))
+	}
+	return collectors, nil
+}
+
+// haFlags registers the flag listing the NameNodes of a nameservice whose HA
+// state is watched. The returned function creates the collector once the
+// flags have been parsed, or returns nil if the flag is not set.
+func haFlags(fs *flag.FlagSet) func(client *jmx.Client) (*collector.HA, error) {
+	list := fs.String("namenode.ha.namenodes", "", "NameNodes of the nameservice whose HA state is watched, as comma-separated id=JMX URL pairs, e.g. nn1=http://nn1:9870/jmx,nn2=http://nn2:9870/jmx.")
+	return func(client *jmx.Client) (*collector.HA, error) {
+		if *list == "" {
+			return nil, nil
+		}
+		var namenodes []collector.HANameNode
+		for _, nn := range strings.Split(*list, ",") {
+			i := strings.Index(nn, "=")
+			if i <= 0 {
+				return nil, fmt.Errorf("-namenode.ha.namenodes: %q is not an id=URL pair", nn)
+			}
+			namenodes = append(namenodes, collector.HANameNode{Name: nn[:i], URL: nn[i+1:], Client: client})
+		}
+		return collector.NewHA(namenodes, nil), nil
+	}
+}
+
+// loadRules compiles the rules of every role.
+func loadRules(rulesFile string) (map[string]*rules.Set, error) {
+	ruleSets := map[string]*rules.Set{}
+	for _, role := range collector.Roles {
+		r, err := rules.Load(role, rulesFile)
+		if err != nil {
+			return nil, err
+		}
+		if ruleSets[role], err = rules.New(role, r); err != nil {
+			return nil, err
+		}
+	}
+	return ruleSets, nil
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, name := range collector.Roles {
+		fmt.Fprintf(os.Stderr, "  %-16s export %s metrics\n", name, roles[name].title)
+	}
+	fmt.Fprintf(os.Stderr, "  %-16s export the metrics of every role\n", "all")
+	fmt.Fprintf(os.Stderr, "  %-16s export the metrics of the roles detected at a JMX URL\n", "auto")
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for the flags of a command.\n", os.Args[0])
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	command := os.Args[1]
+	var names []string
+	title := "Hadoop"
+	listen := ":9070"
+	switch r, ok := roles[command]; {
+	case ok:
+		names = []string{command}
+		title, listen = r.title, r.listenAddress
+	case command == "all":
+		names = collector.Roles
+	case command == "auto":
+		// The roles are detected once the flags are parsed.
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	// Shared flags registered by libraries, such as -log.level.
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		fs.Var(f.Value, f.Name, f.Usage)
+	})
+	listenAddress := fs.String("web.listen-address", listen, "Address on which to expose metrics and web interface.")
+	metricsPath := fs.String("web.telemetry-path", "/metrics", "Path under which to expose metrics.")
+	rulesFile := fs.String("rules.file", "", "Path to a YAML file of bean-to-metric rules evaluated before the built-in ones.")
+	webConfigFile := fs.String("web.config.file", "", "Path to a web configuration file enabling TLS and basic authentication on the listener, in the format of the Prometheus exporter-toolkit.")
+	configFile := fs.String("config.file", "", "Path to a YAML file describing the clusters to scrape. Role URL flags that are set explicitly override the role's targets.")
+	newClient := clientFlags(fs)
+	var newHA func(*jmx.Client) (*collector.HA, error)
+	for _, name := range names {
+		if name == "namenode" {
+			newHA = haFlags(fs)
+		}
+	}
+	constructors := make([]func(collector.Options) (*collector.Exporter, error), len(names))
+	for i, name := range names {
+		constructors[i] = roles[name].flags(fs)
+	}
+	var jmxURL *string
+	if command == "auto" {
+		jmxURL = autoFlags(fs)
+	}
+	fs.Parse(os.Args[2:])
+
+	if *configFile != "" && jmxURL != nil {
+		log.Fatal("-config.file cannot be used with the auto command")
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	// load reads the rules and configuration files and creates the
+	// collectors. It runs again on every reload.
+	load := func() (*state, error) {
+		ruleSets, err := loadRules(*rulesFile)
+		if err != nil {
+			return nil, err
+		}
+		var cfg *config.Config
+		if *configFile != "" {
+			if cfg, err = config.Load(*configFile); err != nil {
+				return nil, err
+			}
+		}
+		client, probeClient, err := newClient()
+		if err != nil {
+			return nil, err
+		}
+		s := &state{ruleSets: ruleSets, probeClient: probeClient, clients: map[string]*jmx.Client{}}
+		for i, name := range names {
+			if cfg != nil && !set[roles[name].urlFlag] {
+				exporters, err := configExporters(cfg, name, ruleSets)
+				if err != nil {
+					return nil, err
+				}
+				s.exporters = append(s.exporters, exporters...)
+				continue
+			}
+			exporter, err := constructors[i](collector.Options{Rules: ruleSets[name], Client: client})
+			if err != nil {
+				return nil, err
+			}
+			s.exporters = append(s.exporters, exporter)
+		}
+		switch {
+		case newHA == nil:
+		case set["namenode.ha.namenodes"] || cfg == nil || set["namenode.jmx.url"]:
+			ha, err := newHA(client)
+			if err != nil {
+				return nil, err
+			}
+			if ha != nil {
+				s.collectors = append(s.collectors, ha)
+			}
+		default:
+			ha, err := configHA(cfg)
+			if err != nil {
+				return nil, err
+			}
+			for _, c := range ha {
+				s.collectors = append(s.collectors, c)
+			}
+		}
+		if jmxURL != nil {
+			// A daemon that is not up yet is detected on a later scrape.
+			s.auto = newAutoDetector(*jmxURL, ruleSets, client)
+			s.collectors = append(s.collectors, s.auto)
+			s.addClient(*jmxURL, client)
+		}
+		for _, e := range s.exporters {
+			s.addClient(e.URL(), e.Client())
+		}
+		return s, nil
+	}
+	reloader, err := newReloader(load)
+	if err != nil {
+		log.Fatal(err)
+	}
+	prometheus.MustRegister(reloader)
+	reloader.watchSignals()
+
+	log.Printf("Starting Server: %s", *listenAddress)
+	http.Handle(*metricsPath, promhttp.Handler())
+	http.Handle("/probe", probeHandler(reloader.current))
+	http.Handle("/-/reload", reloader.handler())
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html>
+		<head><title>` + title + ` Exporter</title></head>
+		<body>
+		<h1>` + title + ` Exporter</h1>
+		<p>Roles: ` + strings.Join(reloader.roles(), ", ") + `</p>
+		<p><a href="` + *metricsPath + `">Metrics</a></p>
+		<p><a href="/probe?target=http://localhost:50075/jmx&module=datanode">Probe a DataNode</a></p>
+		</body>
+		</html>`))
+	})
+	err = web.ListenAndServe(&http.Server{Addr: *listenAddress}, *webConfigFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/log"
+	"github.com/wyukawa/hadoop_exporter/collector"
+)
+
+var (
+	probeSuccessDesc = prometheus.NewDesc(
+		"probe_success",
+		"Whether every role of the target could be scraped.",
+		nil, nil,
+	)
+	probeDurationDesc = prometheus.NewDesc(
+		"probe_duration_seconds",
+		"How long the probe took, in seconds.",
+		nil, nil,
+	)
+)
+
+// probeCollector scrapes the collectors of one probe and reports the outcome.
+type probeCollector struct {
+	exporters []*collector.Exporter
+	// doc is the document the roles were detected from, if any. It is
+	// scraped instead of being fetched again.
+	doc *collector.Document
+	// err is set if the collectors could not be created.
+	err error
+}
+
+// Describe implements the prometheus.Collector interface.
+func (p *probeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- probeSuccessDesc
+	ch <- probeDurationDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (p *probeCollector) Collect(ch chan<- prometheus.Metric) {
+	start := time.Now()
+	success := p.err == nil
+	for _, e := range p.exporters {
+		if !e.ScrapeDocument(p.doc, ch) {
+			success = false
+		}
+	}
+	var v float64
+	if success {
+		v = 1
+	}
+	ch <- prometheus.MustNewConstMetric(probeSuccessDesc, prometheus.GaugeValue, v)
+	ch <- prometheus.MustNewConstMetric(probeDurationDesc, prometheus.GaugeValue, time.Since(start).Seconds())
+}
+
+// probeHandler serves /probe?target=<url>&module=<role>, scraping the target
+// with the collector of the role given as module, in the style of the
+// blackbox_exporter. With module=auto, or without a module, the roles are
+// detected from the target, which must then be a /jmx URL, and the document
+// they were detected from is scraped without fetching it again. The rules and
+// client are those current when the request is served.
+func probeHandler(current func() *state) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := r.URL.Query().Get("target")
+		if target == "" {
+			http.Error(w, "target parameter is missing", http.StatusBadRequest)
+			return
+		}
+		module := r.URL.Query().Get("module")
+		if module == "" {
+			module = "auto"
+		}
+
+		s := current()
+		client := s.clientFor(target)
+		p := &probeCollector{}
+		if module == "auto" {
+			if p.doc, p.err = collector.FetchDocument(target, client); p.err == nil {
+				p.exporters, p.err = documentExporters(p.doc, s.ruleSets, client)
+			}
+		} else {
+			ruleSet, ok := s.ruleSets[module]
+			if !ok {
+				http.Error(w, fmt.Sprintf("unknown module %q", module), http.StatusBadRequest)
+				return
+			}
+			var e *collector.Exporter
+			if e, p.err = collector.New(module, target, collector.Options{Rules: ruleSet, Client: client}); p.err == nil {
+				p.exporters = append(p.exporters, e)
+			}
+		}
+		if p.err != nil {
+			log.Errorf("probe %s: %v", target, p.err)
+		}
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(p)
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+func TestProbeHandler(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		fetches = map[string]int{}
+	)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fetches[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/namenode/jmx":
+			fmt.Fprint(w, `{"beans":[{"name":"Hadoop:service=NameNode,name=FSNamesystem","MissingBlocks":3}]}`)
+		case "/datanode/jmx":
+			fmt.Fprint(w, `{"beans":[{"name":"Hadoop:service=DataNode,name=DataNodeInfo"}]}`)
+		case "/other/jmx":
+			fmt.Fprint(w, `{"beans":[{"name":"java.lang:type=Memory"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer target.Close()
+
+	ruleSets, err := loadRules("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &state{ruleSets: ruleSets, probeClient: jmx.DefaultClient, clients: map[string]*jmx.Client{}}
+	srv := httptest.NewServer(probeHandler(func() *state { return s }))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name   string
+		query  string
+		status int
+		// want are lines expected in the body.
+		want []string
+		// fetches is the number of requests expected for the target.
+		fetches int
+	}{
+		{name: "no target", query: "module=datanode", status: http.StatusBadRequest},
+		{name: "unknown module", query: "target=" + target.URL + "/datanode/jmx&module=hbase", status: http.StatusBadRequest},
+		{
+			name:    "module",
+			query:   "target=" + target.URL + "/datanode/jmx&module=datanode",
+			status:  http.StatusOK,
+			want:    []string{"datanode_up 1", "probe_success 1"},
+			fetches: 1,
+		},
+		{
+			// The document the roles are detected from is scraped.
+			name:    "auto",
+			query:   "target=" + target.URL + "/namenode/jmx",
+			status:  http.StatusOK,
+			want:    []string{"namenode_up 1", "namenode_missing_blocks 3", "probe_success 1"},
+			fetches: 1,
+		},
+		{
+			name:    "module on a failing target",
+			query:   "target=" + target.URL + "/missing/jmx&module=namenode",
+			status:  http.StatusOK,
+			want:    []string{"namenode_up 0", "probe_success 0"},
+			fetches: 1,
+		},
+		{
+			name:    "auto on an unknown service",
+			query:   "target=" + target.URL + "/other/jmx&module=auto",
+			status:  http.StatusOK,
+			want:    []string{"probe_success 0"},
+			fetches: 1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mu.Lock()
+			fetches = map[string]int{}
+			mu.Unlock()
+			resp, err := http.Get(srv.URL + "/probe?" + tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tc.status {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tc.status, body)
+			}
+			lines := map[string]bool{}
+			for _, l := range strings.Split(string(body), "\n") {
+				lines[l] = true
+			}
+			for _, l := range tc.want {
+				if !lines[l] {
+					t.Errorf("missing %q in\n%s", l, body)
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			n := 0
+			for _, c := range fetches {
+				n += c
+			}
+			if n != tc.fetches {
+				t.Errorf("target fetched %d times, want %d", n, tc.fetches)
+			}
+		})
+	}
+}
+
+func TestProbeCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "probe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	newClients := clientFlags(fs)
+	if err := fs.Parse([]string{"-auth.user-name=hdfs", "-auth.delegation-token-file=" + tokenFile}); err != nil {
+		t.Fatal(err)
+	}
+	client, probeClient, err := newClients()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// credentials returns a daemon recording the credentials it is sent.
+	credentials := func(got *[]string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*got = append(*got, r.URL.Query().Get("user.name")+"/"+r.Header.Get("X-Hadoop-Delegation-Token"))
+			fmt.Fprint(w, `{"beans":[{"name":"Hadoop:service=DataNode,name=DataNodeInfo"}]}`)
+		}))
+	}
+	var configuredGot, otherGot []string
+	configured := credentials(&configuredGot)
+	defer configured.Close()
+	other := credentials(&otherGot)
+	defer other.Close()
+
+	ruleSets, err := loadRules("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &state{ruleSets: ruleSets, probeClient: probeClient, clients: map[string]*jmx.Client{}}
+	s.addClient(configured.URL+"/jmx", client)
+	srv := httptest.NewServer(probeHandler(func() *state { return s }))
+	defer srv.Close()
+
+	for _, query := range []string{"module=datanode", "module=auto"} {
+		for _, target := range []string{configured.URL, other.URL} {
+			resp, err := http.Get(srv.URL + "/probe?target=" + target + "/jmx&" + query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+		}
+	}
+	// Configured targets are probed with their credentials, in both modes,
+	// and other hosts never get them.
+	if want := []string{"hdfs/token", "hdfs/token"}; strings.Join(configuredGot, ",") != strings.Join(want, ",") {
+		t.Errorf("the configured target got %q, want %q", configuredGot, want)
+	}
+	if want := []string{"/", "/"}; strings.Join(otherGot, ",") != strings.Join(want, ",") {
+		t.Errorf("another host got %q, want %q", otherGot, want)
+	}
+}
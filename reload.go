@@ -0,0 +1,214 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/log"
+	"github.com/wyukawa/hadoop_exporter/collector"
+	"github.com/wyukawa/hadoop_exporter/httpclient"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+	"github.com/wyukawa/hadoop_exporter/rules"
+)
+
+// state is what is built from the configuration: the collectors exported on
+// the metrics path, and the rules and clients used by probes.
+type state struct {
+	exporters []*collector.Exporter
+	// collectors are the collectors spanning several daemons, or whose
+	// daemons are not known in advance.
+	collectors []prometheus.Collector
+	// auto detects the roles to export in auto mode.
+	auto     *autoDetector
+	ruleSets map[string]*rules.Set
+	// probeClient fetches the probed targets that are not configured. It
+	// does not send credentials, which would go to whoever the caller of
+	// /probe names.
+	probeClient *jmx.Client
+	// clients are the clients of the configured targets, by host and port,
+	// used to probe them.
+	clients map[string]*jmx.Client
+}
+
+// addClient records client as the client of the host of rawurl.
+func (s *state) addClient(rawurl string, client *jmx.Client) {
+	if u, err := url.Parse(rawurl); err == nil && u.Host != "" {
+		s.clients[u.Host] = client
+	}
+}
+
+// clientFor returns the client to probe target with.
+func (s *state) clientFor(target string) *jmx.Client {
+	if u, err := url.Parse(target); err == nil {
+		if client, ok := s.clients[u.Host]; ok {
+			return client
+		}
+	}
+	return s.probeClient
+}
+
+// inherit makes the exporters of s continue the scrape error counts of those
+// of prev scraping the same daemons.
+func (s *state) inherit(prev *state) {
+	previous := map[string][]*collector.Exporter{}
+	for _, e := range prev.exporters {
+		previous[e.URL()] = append(previous[e.URL()], e)
+	}
+	for _, e := range s.exporters {
+		for _, p := range previous[e.URL()] {
+			e.Inherit(p)
+		}
+	}
+	if s.auto != nil && prev.auto != nil {
+		s.auto.inherit(prev.auto.current())
+	}
+}
+
+// reloader exports the collectors of the current state and replaces the state
+// when the configuration is reloaded. A configuration that fails to load is
+// reported and the previous state kept.
+type reloader struct {
+	load func() (*state, error)
+
+	// reloadMu serializes reloads.
+	reloadMu sync.Mutex
+	mu       sync.RWMutex
+	state    *state
+
+	lastReloadSuccessful       prometheus.Gauge
+	lastReloadSuccessTimestamp prometheus.Gauge
+}
+
+// newReloader returns a reloader with the state returned by load.
+func newReloader(load func() (*state, error)) (*reloader, error) {
+	r := &reloader{
+		load: load,
+		lastReloadSuccessful: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "hadoop_exporter",
+			Name:      "config_last_reload_successful",
+			Help:      "Whether the last configuration reload succeeded.",
+		}),
+		lastReloadSuccessTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "hadoop_exporter",
+			Name:      "config_last_reload_success_timestamp_seconds",
+			Help:      "Timestamp of the last successful configuration reload.",
+		}),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the configuration again and swaps the state if it succeeds.
+func (r *reloader) Reload() error {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+	endKerberosGeneration := httpclient.BeginKerberosGeneration()
+	s, err := r.load()
+	if err != nil {
+		endKerberosGeneration(false)
+		r.lastReloadSuccessful.Set(0)
+		return err
+	}
+	if prev := r.current(); prev != nil {
+		s.inherit(prev)
+	}
+	r.mu.Lock()
+	r.state = s
+	r.mu.Unlock()
+	endKerberosGeneration(true)
+	r.lastReloadSuccessful.Set(1)
+	r.lastReloadSuccessTimestamp.Set(float64(time.Now().Unix()))
+	return nil
+}
+
+func (r *reloader) current() *state {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.state
+}
+
+// roles returns the roles of the current collectors.
+func (r *reloader) roles() []string {
+	var names []string
+	seen := map[string]bool{}
+	s := r.current()
+	exporters := s.exporters
+	if s.auto != nil {
+		exporters = append(append([]*collector.Exporter{}, exporters...), s.auto.current()...)
+	}
+	for _, e := range exporters {
+		if !seen[e.Role()] {
+			seen[e.Role()] = true
+			names = append(names, e.Role())
+		}
+	}
+	return names
+}
+
+// Describe implements the prometheus.Collector interface. The collectors
+// change with the configuration, so only the reload metrics are described.
+func (r *reloader) Describe(ch chan<- *prometheus.Desc) {
+	r.lastReloadSuccessful.Describe(ch)
+	r.lastReloadSuccessTimestamp.Describe(ch)
+}
+
+// Collect implements the prometheus.Collector interface. The collectors are
+// scraped in parallel.
+func (r *reloader) Collect(ch chan<- prometheus.Metric) {
+	r.lastReloadSuccessful.Collect(ch)
+	r.lastReloadSuccessTimestamp.Collect(ch)
+	s := r.current()
+	collectors := append([]prometheus.Collector{}, s.collectors...)
+	for _, e := range s.exporters {
+		collectors = append(collectors, e)
+	}
+	var wg sync.WaitGroup
+	for _, c := range collectors {
+		wg.Add(1)
+		go func(c prometheus.Collector) {
+			defer wg.Done()
+			c.Collect(ch)
+		}(c)
+	}
+	wg.Wait()
+}
+
+// watchSignals reloads the configuration on SIGHUP.
+func (r *reloader) watchSignals() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := r.Reload(); err != nil {
+				log.Errorf("Error reloading configuration: %v", err)
+				continue
+			}
+			log.Info("Reloaded configuration")
+		}
+	}()
+}
+
+// handler reloads the configuration on POST requests.
+func (r *reloader) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "only POST requests are allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := r.Reload(); err != nil {
+			log.Errorf("Error reloading configuration: %v", err)
+			http.Error(w, "failed to reload configuration: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Info("Reloaded configuration")
+	})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wyukawa/hadoop_exporter/collector"
+)
+
+// gatherValue gathers r and returns the value of the series of name whose
+// labels include labels, or -1 if there is none.
+func gatherValue(t *testing.T, r prometheus.Collector, name string, labels map[string]string) float64 {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(r)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range f.Metric {
+			have := map[string]string{}
+			for _, l := range m.Label {
+				have[l.GetName()] = l.GetValue()
+			}
+			for k, v := range labels {
+				if have[k] != v {
+					continue metrics
+				}
+			}
+			switch {
+			case m.Gauge != nil:
+				return m.Gauge.GetValue()
+			case m.Counter != nil:
+				return m.Counter.GetValue()
+			}
+			return m.Untyped.GetValue()
+		}
+	}
+	return -1
+}
+
+func TestReloader(t *testing.T) {
+	// The daemon is down, so that every scrape counts an error.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	// load builds the state from labels, those of its only exporter, or
+	// fails with loadErr. Every gathering scrapes the exporter once.
+	var (
+		labels  map[string]string
+		loadErr error
+	)
+	load := func() (*state, error) {
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		e, err := collector.New("namenode", srv.URL+"/jmx", collector.Options{Collectors: []string{"rules"}, Labels: labels})
+		if err != nil {
+			return nil, err
+		}
+		return &state{exporters: []*collector.Exporter{e}}, nil
+	}
+
+	labels = map[string]string{"cluster": "a"}
+	r, err := newReloader(load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errorsTotal := func(cluster string) float64 {
+		return gatherValue(t, r, "namenode_scrape_errors_total", map[string]string{"cluster": cluster, "reason": "status"})
+	}
+	if got := errorsTotal("a"); got != 1 {
+		t.Fatalf("scrape_errors_total = %v, want 1", got)
+	}
+
+	// The new state replaces the old one, and continues its counts.
+	labels = map[string]string{"cluster": "a"}
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := errorsTotal("a"); got != 2 {
+		t.Errorf("after a reload, scrape_errors_total = %v, want 2", got)
+	}
+
+	// A configuration that fails to load keeps the previous state.
+	prev := r.current()
+	loadErr = errors.New("invalid configuration")
+	if err := r.Reload(); err != loadErr {
+		t.Errorf("Reload() = %v, want %v", err, loadErr)
+	}
+	if r.current() != prev {
+		t.Error("the state was replaced by a failed reload")
+	}
+	if got := errorsTotal("a"); got != 3 {
+		t.Errorf("after a failed reload, scrape_errors_total = %v, want 3", got)
+	}
+	if got := gatherValue(t, r, "hadoop_exporter_config_last_reload_successful", nil); got != 0 {
+		t.Errorf("config_last_reload_successful = %v, want 0", got)
+	}
+
+	// Exporters with other labels start from zero.
+	loadErr = nil
+	labels = map[string]string{"cluster": "b"}
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := errorsTotal("b"); got != 1 {
+		t.Errorf("with new labels, scrape_errors_total = %v, want 1", got)
+	}
+	if got := errorsTotal("a"); got != -1 {
+		t.Errorf("the previous exporter is still exported: %v", got)
+	}
+	if got := gatherValue(t, r, "hadoop_exporter_config_last_reload_successful", nil); got != 1 {
+		t.Errorf("config_last_reload_successful = %v, want 1", got)
+	}
+}
@@ -0,0 +1,329 @@
+package rules
+
+// Scales converting Hadoop's units to base units.
+const (
+	millis    = 1e-3
+	mebibytes = 1 << 20
+	gibibytes = 1 << 30
+)
+
+var defaults = map[string][]Rule{
+	"namenode": {
+		{
+			Bean:      `Hadoop:service=NameNode,name=FSNamesystem`,
+			Attribute: `MissingBlocks|CorruptBlocks|ExcessBlocks|StaleDataNodes`,
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=FSNamesystem`,
+			Attribute: `(Blocks|Files)Total`,
+			Name:      "$1",
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=FSNamesystem`,
+			Attribute: `Capacity(Total|Used|Remaining|UsedNonDFS)`,
+			Name:      "capacity_${1}_bytes",
+		},
+		// TotalSyncCount restarts from 0 when the edit log is opened for
+		// write, on startup and on every transition to active.
+		{
+			Bean:      `Hadoop:service=NameNode,name=FSNamesystem`,
+			Attribute: `(TotalSyncCount)`,
+			Name:      "${1}_total",
+			Type:      Counter,
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=FSNamesystem`,
+			Attribute: `TransactionsSinceLastCheckpoint|TransactionsSinceLastLogRoll|LastWrittenTransactionId`,
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=FSNamesystem`,
+			Attribute: `LastCheckpointTime`,
+			Name:      "last_checkpoint_timestamp_seconds",
+			Scale:     millis,
+		},
+		// State is exported by is_active, and by the HA collector.
+		{
+			Bean:      `Hadoop:service=NameNode,name=NameNodeStatus`,
+			Attribute: `SecurityEnabled|BytesWithFutureGenerationStamps`,
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=NameNodeStatus`,
+			Attribute: `LastHATransitionTime`,
+			Name:      "last_ha_transition_timestamp_seconds",
+			Scale:     millis,
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=FSNamesystem`,
+			Attribute: `tag\.HAState`,
+			Name:      "is_active",
+			Values:    map[string]float64{"active": 1},
+		},
+		// Replication and, from Hadoop 3, erasure coded reconstruction. The
+		// Hadoop 2 names (UnderReplicatedBlocks, PendingReplicationBlocks...)
+		// are kept by Hadoop 3 next to the new ones.
+		{
+			Bean:      `Hadoop:service=NameNode,name=FSNamesystem`,
+			Attribute: `UnderReplicatedBlocks|PendingReplicationBlocks|PendingDeletionBlocks|ScheduledReplicationBlocks|PostponedMisreplicatedBlocks|MissingReplOneBlocks|CorruptReplicaBlocks|LowRedundancyBlocks|PendingReconstructionBlocks|LowRedundancyECBlockGroups|CorruptECBlockGroups|MissingECBlockGroups`,
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=(?:ReplicatedBlocksState|ECBlockGroupsState)`,
+			Attribute: `\w+(?:Blocks|BlockGroups)`,
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=FSNamesystemState`,
+			Attribute: `NumSnapshots|NumSnapshottableDirs|NumEncryptionZones`,
+		},
+		// DataNodes by state. Per-node decommissioning progress is exported
+		// by the datanodes collector.
+		{
+			Bean:      `Hadoop:service=NameNode,name=FSNamesystemState`,
+			Attribute: `Num(?:Live|Dead|Decommissioning|DecomLive|DecomDead|InMaintenanceLive|InMaintenanceDead|EnteringMaintenance)DataNodes`,
+		},
+		// FSState is safeMode or Operational.
+		{
+			Bean:      `Hadoop:service=NameNode,name=FSNamesystemState`,
+			Attribute: `FSState`,
+			Name:      "in_safemode",
+			Values:    map[string]float64{"safeMode": 1},
+		},
+		// Startup progress, overall and per phase. The steps of a phase are
+		// the inodes loaded from the image, edits applied, inodes saved or
+		// safe blocks reported. PercentComplete goes from 0 to 1.
+		{Bean: `Hadoop:service=NameNode,name=StartupProgress`, Attribute: `ElapsedTime`, Name: "startup_elapsed_seconds", Scale: millis},
+		{Bean: `Hadoop:service=NameNode,name=StartupProgress`, Attribute: `PercentComplete`, Name: "startup_complete_ratio"},
+		{
+			Bean:      `Hadoop:service=NameNode,name=StartupProgress`,
+			Attribute: `(LoadingFsImage|LoadingEdits|SavingCheckpoint|SafeMode)Count`,
+			Name:      "startup_phase_steps_done",
+			Labels:    map[string]string{"phase": "$1"},
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=StartupProgress`,
+			Attribute: `(LoadingFsImage|LoadingEdits|SavingCheckpoint|SafeMode)Total`,
+			Name:      "startup_phase_steps",
+			Labels:    map[string]string{"phase": "$1"},
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=StartupProgress`,
+			Attribute: `(LoadingFsImage|LoadingEdits|SavingCheckpoint|SafeMode)ElapsedTime`,
+			Name:      "startup_phase_elapsed_seconds",
+			Labels:    map[string]string{"phase": "$1"},
+			Scale:     millis,
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=StartupProgress`,
+			Attribute: `(LoadingFsImage|LoadingEdits|SavingCheckpoint|SafeMode)PercentComplete`,
+			Name:      "startup_phase_complete_ratio",
+			Labels:    map[string]string{"phase": "$1"},
+		},
+		// NameNodeActivity counts operations since the NameNode started, and
+		// averages their latency over the metrics period. Percentiles are
+		// exported when dfs.metrics.percentiles.intervals is set. Times are
+		// in milliseconds.
+		{
+			Bean:      `Hadoop:service=NameNode,name=NameNodeActivity`,
+			Attribute: `([A-Za-z]+Ops|Files(?:Created|Appended|Renamed|Truncated|Deleted)|GetBlockLocations|SuccessfulReReplications|NumTimesReReplicationNotScheduled|TimeoutReReplications|TransactionsBatchedInSync)`,
+			Name:      "${1}_total",
+			Type:      Counter,
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=NameNodeActivity`,
+			Attribute: `(\w+)AvgTime`,
+			Name:      "${1}_avg_seconds",
+			Scale:     millis,
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=NameNodeActivity`,
+			Attribute: `(SafeModeTime|FsImageLoadTime)`,
+			Name:      "${1}_seconds",
+			Scale:     millis,
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=NameNodeActivity`,
+			Attribute: `BlockOpsQueued|BlockOpsBatched`,
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=NameNodeActivity`,
+			Attribute: `([A-Za-z]+)(\d+)s(\d+)thPercentileLatency`,
+			Name:      "activity_percentile_latency_seconds",
+			Labels:    map[string]string{"op": "$1", "interval": "${2}s", "percentile": "$3"},
+			Scale:     millis,
+		},
+		// One bean per RPC server: the client port, and the service RPC and
+		// lifeline ports when they are configured. Times are in milliseconds,
+		// the default of rpc.metrics.timeunit.
+		{
+			Bean:      `Hadoop:service=NameNode,name=RpcActivityForPort(\d+)`,
+			Attribute: `(RpcQueueTimeNumOps|RpcProcessingTimeNumOps|NumDroppedConnections|RpcAuthenticationFailures|RpcAuthenticationSuccesses|RpcAuthorizationFailures|RpcAuthorizationSuccesses|RpcSlowCalls|ReceivedBytes|SentBytes)`,
+			Name:      "${2}_total",
+			Type:      Counter,
+			Labels:    map[string]string{"port": "$1"},
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=RpcActivityForPort(\d+)`,
+			Attribute: `(Rpc(?:Queue|Processing)Time)AvgTime`,
+			Name:      "${2}_avg_seconds",
+			Labels:    map[string]string{"port": "$1"},
+			Scale:     millis,
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=RpcActivityForPort(\d+)`,
+			Attribute: `CallQueueLength|NumOpenConnections`,
+			Labels:    map[string]string{"port": "$1"},
+		},
+		// Percentiles are exported when rpc.metrics.percentiles.intervals is
+		// set, as e.g. RpcQueueTime60s99thPercentileLatency.
+		{
+			Bean:      `Hadoop:service=NameNode,name=RpcActivityForPort(\d+)`,
+			Attribute: `RpcQueueTime(\d+)s(\d+)thPercentileLatency`,
+			Name:      "rpc_queue_time_percentile_latency_seconds",
+			Labels:    map[string]string{"port": "$1", "interval": "${2}s", "percentile": "$3"},
+			Scale:     millis,
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=RpcActivityForPort(\d+)`,
+			Attribute: `RpcProcessingTime(\d+)s(\d+)thPercentileLatency`,
+			Name:      "rpc_processing_time_percentile_latency_seconds",
+			Labels:    map[string]string{"port": "$1", "interval": "${2}s", "percentile": "$3"},
+			Scale:     millis,
+		},
+		// The FairCallQueue's scheduler, per RPC server. Per-user volumes are
+		// exported by the top_users collector.
+		{
+			Bean:      `Hadoop:service=NameNode,name=DecayRpcSchedulerMetrics2\.ipc\.(\d+)`,
+			Attribute: `DecayedCallVolume`,
+			Name:      "decay_rpc_scheduler_decayed_call_volume",
+			Labels:    map[string]string{"port": "$1"},
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=DecayRpcSchedulerMetrics2\.ipc\.(\d+)`,
+			Attribute: `CallVolume`,
+			Name:      "decay_rpc_scheduler_call_volume",
+			Labels:    map[string]string{"port": "$1"},
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=DecayRpcSchedulerMetrics2\.ipc\.(\d+)`,
+			Attribute: `UniqueCallers`,
+			Name:      "decay_rpc_scheduler_unique_callers",
+			Labels:    map[string]string{"port": "$1"},
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=DecayRpcSchedulerMetrics2\.ipc\.(\d+)`,
+			Attribute: `Priority\.(\d+)\.AvgResponseTime`,
+			Name:      "decay_rpc_scheduler_avg_response_time_seconds",
+			Labels:    map[string]string{"port": "$1", "priority": "$2"},
+			Scale:     millis,
+		},
+		{
+			Bean:      `Hadoop:service=NameNode,name=DecayRpcSchedulerMetrics2\.ipc\.(\d+)`,
+			Attribute: `Priority\.(\d+)\.CompletedCallVolume`,
+			Name:      "decay_rpc_scheduler_completed_call_volume",
+			Labels:    map[string]string{"port": "$1", "priority": "$2"},
+		},
+	},
+	"datanode": {
+		{
+			Bean:      `Hadoop:service=DataNode,name=FSDatasetState(?:-.*)?`,
+			Attribute: `(Capacity|DfsUsed|Remaining|CacheUsed|CacheCapacity)`,
+			Name:      "${1}_bytes",
+		},
+		{
+			Bean:      `Hadoop:service=DataNode,name=FSDatasetState(?:-.*)?`,
+			Attribute: `EstimatedCapacityLostTotal`,
+			Name:      "estimated_capacity_lost_bytes",
+		},
+		{
+			Bean:      `Hadoop:service=DataNode,name=FSDatasetState(?:-.*)?`,
+			Attribute: `NumFailedVolumes`,
+		},
+		// LastVolumeFailureDate is 0 until a volume fails.
+		{
+			Bean:      `Hadoop:service=DataNode,name=FSDatasetState(?:-.*)?`,
+			Attribute: `LastVolumeFailureDate`,
+			Name:      "last_volume_failure_timestamp_seconds",
+			Scale:     millis,
+		},
+	},
+	"journalnode": {
+		{
+			Bean:      `Hadoop:service=JournalNode,name=Journal-(.+)`,
+			Attribute: `Syncs60sNumOps`,
+			Name:      "syncs_num_ops",
+			Labels:    map[string]string{"journal": "$1"},
+		},
+		{
+			Bean:      `Hadoop:service=JournalNode,name=Journal-(.+)`,
+			Attribute: `(BatchesWritten|TxnsWritten|BytesWritten|BatchesWrittenWhileLagging)`,
+			Name:      "${2}_total",
+			Type:      Counter,
+			Labels:    map[string]string{"journal": "$1"},
+		},
+		{
+			Bean:      `Hadoop:service=JournalNode,name=Journal-(.+)`,
+			Attribute: `LastWrittenTxId|LastPromisedEpoch|LastWriterEpoch|CurrentLagTxns`,
+			Labels:    map[string]string{"journal": "$1"},
+		},
+		{
+			Bean:      `Hadoop:service=JournalNode,name=Journal-(.+)`,
+			Attribute: `LastJournalTimestamp`,
+			Name:      "last_journal_timestamp_seconds",
+			Labels:    map[string]string{"journal": "$1"},
+			Scale:     millis,
+		},
+	},
+	"nodemanager": {
+		{
+			Bean:      `Hadoop:service=NodeManager,name=NodeManagerMetrics`,
+			Attribute: `(ContainersLaunched|ContainersCompleted|ContainersFailed|ContainersKilled)`,
+			Name:      "${1}_total",
+			Type:      Counter,
+			Int32:     true,
+		},
+		{
+			Bean:      `Hadoop:service=NodeManager,name=NodeManagerMetrics`,
+			Attribute: `ContainersIniting|ContainersRunning|AllocatedContainers`,
+		},
+		{
+			Bean:      `Hadoop:service=NodeManager,name=NodeManagerMetrics`,
+			Attribute: `(Allocated|Available)GB`,
+			Name:      "${1}_memory_bytes",
+			Scale:     gibibytes,
+		},
+		{
+			Bean:      `Hadoop:service=NodeManager,name=NodeManagerMetrics`,
+			Attribute: `(Allocated|Available)VCores`,
+			Name:      "${1}_vcores",
+		},
+	},
+	// The JobHistoryServer's metrics are those of the jvm collector.
+	"jobhistoryserver": {},
+	// The ResourceManager's /ws/v1/cluster/metrics response is presented to
+	// the rules as a single bean named clusterMetrics, next to its /jmx beans.
+	"resourcemanager": {
+		// The application counters restart from 0 on the ResourceManager
+		// that becomes active after a failover. appsFailed is a gauge in
+		// Hadoop's QueueMetrics and stays one.
+		{
+			Bean:      `clusterMetrics`,
+			Attribute: `(appsKilled|appsCompleted|appsSubmitted)`,
+			Name:      "${1}_total",
+			Type:      Counter,
+			Int32:     true,
+		},
+		{
+			Bean:      `clusterMetrics`,
+			Attribute: `activeNodes|rebootedNodes|decommissionedNodes|unhealthyNodes|lostNodes|totalNodes|totalVirtualCores|appsFailed|appsRunning|appsPending|reservedVirtualCores|availableVirtualCores|allocatedVirtualCores|containersAllocated|containersReserved|containersPending`,
+		},
+		{
+			Bean:      `clusterMetrics`,
+			Attribute: `(available|reserved|allocated|total)MB`,
+			Name:      "${1}_memory_bytes",
+			Scale:     mebibytes,
+		},
+	},
+}
+
+// Default returns the built-in rules for role.
+func Default(role string) []Rule {
+	return append([]Rule{}, defaults[role]...)
+}
@@ -0,0 +1,296 @@
+// Package rules maps JMX bean attributes to Prometheus metrics.
+//
+// A rule selects attributes by matching a regular expression against the
+// bean's ObjectName and another against the attribute name, and describes the
+// metric to emit for them. Rules are evaluated in order and the first rule that
+// matches an attribute wins, so rules loaded from a file can override the
+// built-in defaults.
+package rules
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Metric types accepted in Rule.Type.
+const (
+	Gauge   = "gauge"
+	Counter = "counter"
+	Untyped = "untyped"
+)
+
+// Rule maps the attributes of matching beans to a metric.
+type Rule struct {
+	// Bean is matched against the whole ObjectName. It defaults to any bean.
+	Bean string `yaml:"bean"`
+	// Attribute is matched against the whole attribute name. Fields of
+	// composite attributes are matched as "Attribute.field". It defaults to
+	// any attribute.
+	Attribute string `yaml:"attribute"`
+	// Name is the metric name without the namespace. It may refer to the
+	// capture groups of Bean and Attribute, numbered across both, as $1 or
+	// ${name}. It defaults to the attribute name. Names are converted to
+	// snake_case, so that RpcQueueTimeAvgTime becomes
+	// rpc_queue_time_avg_time.
+	Name string `yaml:"name"`
+	// Help defaults to the metric name.
+	Help string `yaml:"help"`
+	// Type is gauge, counter or untyped. It defaults to gauge.
+	Type string `yaml:"type"`
+	// Labels are added to the metric. Values may refer to capture groups
+	// like Name.
+	Labels map[string]string `yaml:"labels"`
+	// Values maps string attribute values to numbers. Strings that are not
+	// listed map to 0. Without Values, string attributes are ignored.
+	Values map[string]float64 `yaml:"values"`
+	// Int32 marks counters read from 32-bit Hadoop attributes, which wrap
+	// around to negative values after 2^31-1. The wrap-around is undone.
+	Int32 bool `yaml:"int32"`
+	// Scale multiplies the values, to convert them to base units: 0.001
+	// turns milliseconds into seconds. Values are not scaled if it is 0.
+	Scale float64 `yaml:"scale"`
+}
+
+// File is the format of a rules file.
+type File struct {
+	// ReplaceDefaults drops the built-in rules instead of evaluating the
+	// file's rules before them.
+	ReplaceDefaults bool   `yaml:"replace_defaults"`
+	Rules           []Rule `yaml:"rules"`
+}
+
+// Error is returned for metrics a rule could not produce.
+type Error struct {
+	Rule string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rule %s: %v", e.Rule, e.Err)
+}
+
+// Reason implements the interface used by jmx.Reason.
+func (e *Error) Reason() string {
+	return "rule"
+}
+
+// Load returns the built-in rules for role combined with the rules in the
+// file at path, if path is not empty.
+func Load(role, path string) ([]Rule, error) {
+	defaults := Default(role)
+	if path == "" {
+		return defaults, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if f.ReplaceDefaults {
+		return f.Rules, nil
+	}
+	return append(f.Rules, defaults...), nil
+}
+
+type compiledRule struct {
+	Rule
+	bean      *regexp.Regexp
+	attribute *regexp.Regexp
+	valueType prometheus.ValueType
+	labels    []string
+}
+
+// Set is a compiled list of rules.
+type Set struct {
+	namespace string
+	rules     []compiledRule
+}
+
+// New compiles rules into a Set emitting metrics under namespace.
+func New(namespace string, rules []Rule) (*Set, error) {
+	s := &Set{namespace: namespace}
+	for i, r := range rules {
+		c := compiledRule{Rule: r}
+		bean := r.Bean
+		if bean == "" {
+			bean = ".*"
+		}
+		var err error
+		if c.bean, err = regexp.Compile("^(?:" + bean + ")$"); err != nil {
+			return nil, fmt.Errorf("rule %d: bean: %v", i, err)
+		}
+		attribute := r.Attribute
+		if attribute == "" {
+			attribute = ".*"
+		}
+		// The attribute expression is compiled together with the bean
+		// expression so that capture groups are numbered across both.
+		if c.attribute, err = regexp.Compile("^(?:" + bean + ")\x00(?:" + attribute + ")$"); err != nil {
+			return nil, fmt.Errorf("rule %d: attribute: %v", i, err)
+		}
+		switch r.Type {
+		case Gauge, "":
+			c.valueType = prometheus.GaugeValue
+		case Counter:
+			c.valueType = prometheus.CounterValue
+		case Untyped:
+			c.valueType = prometheus.UntypedValue
+		default:
+			return nil, fmt.Errorf("rule %d: unknown type %q", i, r.Type)
+		}
+		for name := range r.Labels {
+			if !labelNameRE.MatchString(name) {
+				return nil, fmt.Errorf("rule %d: invalid label name %q", i, name)
+			}
+			c.labels = append(c.labels, name)
+		}
+		sort.Strings(c.labels)
+		s.rules = append(s.rules, c)
+	}
+	return s, nil
+}
+
+var (
+	labelNameRE   = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	invalidNameRE = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+	underscoresRE = regexp.MustCompile(`__+`)
+)
+
+// Collect sends the metrics for every attribute of beans matched by a rule
+// to ch. Attributes that match no rule, and values that are not numbers,
+// booleans or mapped strings, are skipped.
+func (s *Set) Collect(beans []jmx.Bean, ch chan<- prometheus.Metric) []error {
+	var errs []error
+	seen := map[string]bool{}
+	for _, bean := range beans {
+		var rules []*compiledRule
+		for i := range s.rules {
+			if s.rules[i].bean.MatchString(bean.Name) {
+				rules = append(rules, &s.rules[i])
+			}
+		}
+		if len(rules) == 0 {
+			continue
+		}
+		attrs := flatten("", bean.Attributes, map[string]interface{}{})
+		names := make([]string, 0, len(attrs))
+		for name := range attrs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, attr := range names {
+			for _, r := range rules {
+				src := bean.Name + "\x00" + attr
+				match := r.attribute.FindStringSubmatchIndex(src)
+				if match == nil {
+					continue
+				}
+				if err := s.collect(r, src, match, attr, attrs[attr], seen, ch); err != nil {
+					errs = append(errs, err)
+				}
+				break
+			}
+		}
+	}
+	return errs
+}
+
+func (s *Set) collect(r *compiledRule, src string, match []int, attr string, raw interface{}, seen map[string]bool, ch chan<- prometheus.Metric) error {
+	v, ok := r.value(raw)
+	if !ok {
+		return nil
+	}
+	if r.Int32 && v < 0 {
+		v += 1 << 32
+	}
+	if r.Scale != 0 {
+		v *= r.Scale
+	}
+	name := attr
+	if r.Name != "" {
+		name = string(r.attribute.ExpandString(nil, r.Name, src, match))
+	}
+	name = prometheus.BuildFQName(s.namespace, "", snakeCase(invalidNameRE.ReplaceAllString(name, "_")))
+	help := r.Help
+	if help == "" {
+		help = strings.TrimPrefix(name, s.namespace+"_")
+	}
+	values := make([]string, len(r.labels))
+	for i, l := range r.labels {
+		values[i] = string(r.attribute.ExpandString(nil, r.Labels[l], src, match))
+	}
+	key := name + "\x00" + strings.Join(values, "\x00")
+	if seen[key] {
+		return nil
+	}
+	seen[key] = true
+	m, err := prometheus.NewConstMetric(prometheus.NewDesc(name, help, r.labels, nil), r.valueType, v, values...)
+	if err != nil {
+		return &Error{Rule: r.Bean + " " + r.Attribute, Err: err}
+	}
+	ch <- m
+	return nil
+}
+
+// snakeCase converts a camelCase name to snake_case. Acronyms are kept as one
+// word: LowRedundancyECBlockGroups becomes low_redundancy_ec_block_groups.
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			acronymEnd := unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || acronymEnd {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return underscoresRE.ReplaceAllString(b.String(), "_")
+}
+
+func (r *compiledRule) value(raw interface{}) (float64, bool) {
+	switch v := raw.(type) {
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		if r.Values == nil {
+			return 0, false
+		}
+		return r.Values[v], true
+	}
+	return 0, false
+}
+
+// flatten adds the attributes in m to out, naming the fields of composite
+// attributes "Attribute.field".
+func flatten(prefix string, m map[string]interface{}, out map[string]interface{}) map[string]interface{} {
+	for k, v := range m {
+		if prefix != "" {
+			k = prefix + "." + k
+		}
+		if sub, ok := v.(map[string]interface{}); ok {
+			flatten(k, sub, out)
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,376 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/wyukawa/hadoop_exporter/jmx"
+)
+
+var fqNameRE = regexp.MustCompile(`fqName: "([^"]*)"`)
+
+// collect applies rules to the beans captured in testdata/jmx.json whose name
+// matches bean, or to all of them if bean is empty, and returns the values of
+// the metrics, keyed like the exposition format.
+func collect(t *testing.T, rules []Rule, bean string) map[string]float64 {
+	t.Helper()
+	data, err := ioutil.ReadFile("testdata/jmx.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp jmx.Response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatal(err)
+	}
+	beans := resp.Beans
+	if bean != "" {
+		re := regexp.MustCompile("^(?:" + bean + ")$")
+		beans = nil
+		for _, b := range resp.Beans {
+			if re.MatchString(b.Name) {
+				beans = append(beans, b)
+			}
+		}
+	}
+	s, err := New("namenode", rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan prometheus.Metric)
+	errc := make(chan []error, 1)
+	go func() {
+		errc <- s.Collect(beans, ch)
+		close(ch)
+	}()
+	metrics := map[string]float64{}
+	for m := range ch {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatal(err)
+		}
+		key := fqNameRE.FindStringSubmatch(m.Desc().String())[1]
+		if len(pb.Label) > 0 {
+			var labels []string
+			for _, l := range pb.Label {
+				labels = append(labels, fmt.Sprintf("%s=%q", l.GetName(), l.GetValue()))
+			}
+			sort.Strings(labels)
+			key += "{" + strings.Join(labels, ",") + "}"
+		}
+		if _, ok := metrics[key]; ok {
+			t.Errorf("duplicate series %s", key)
+		}
+		switch {
+		case pb.Gauge != nil:
+			metrics[key] = pb.Gauge.GetValue()
+		case pb.Counter != nil:
+			metrics[key] = pb.Counter.GetValue()
+		default:
+			metrics[key] = pb.Untyped.GetValue()
+		}
+	}
+	if errs := <-errc; len(errs) != 0 {
+		t.Errorf("errors: %v", errs)
+	}
+	return metrics
+}
+
+func TestCollect(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		rules []Rule
+		// bean, if set, restricts the beans to those it matches.
+		bean string
+		want map[string]float64
+	}{
+		{
+			name: "capture groups numbered across bean and attribute",
+			rules: []Rule{{
+				Bean:      `Hadoop:service=NameNode,name=RpcActivityForPort(\d+)`,
+				Attribute: `Rpc(Queue|Processing)TimeAvgTime`,
+				Name:      "rpc_${2}_avg_time",
+				Labels:    map[string]string{"port": "$1"},
+			}},
+			want: map[string]float64{
+				`namenode_rpc_queue_avg_time{port="8020"}`:      0.25,
+				`namenode_rpc_queue_avg_time{port="8021"}`:      0.5,
+				`namenode_rpc_processing_avg_time{port="8020"}`: 1.5,
+				`namenode_rpc_processing_avg_time{port="8021"}`: 2,
+			},
+		},
+		{
+			name: "first matching rule wins",
+			rules: []Rule{
+				{Bean: `Hadoop:service=NameNode,name=FSNamesystem`, Attribute: `MissingBlocks`, Name: "missing_blocks"},
+				{Bean: `Hadoop:service=NameNode,name=FSNamesystem`, Attribute: `MissingBlocks|BlocksTotal`},
+			},
+			want: map[string]float64{
+				"namenode_missing_blocks": 0,
+				"namenode_blocks_total":   180,
+			},
+		},
+		{
+			// Without a port label, both RPC servers produce the same
+			// series; the first bean's is kept.
+			name: "duplicate series",
+			rules: []Rule{{
+				Bean:      `Hadoop:service=NameNode,name=RpcActivityForPort\d+`,
+				Attribute: `CallQueueLength`,
+			}},
+			want: map[string]float64{
+				"namenode_call_queue_length": 0,
+			},
+		},
+		{
+			name: "string values",
+			rules: []Rule{
+				{
+					Bean:      `Hadoop:service=NameNode,name=FSNamesystem`,
+					Attribute: `tag\.HAState`,
+					Name:      "isActive",
+					Values:    map[string]float64{"active": 1},
+				},
+				{
+					Bean:      `Hadoop:service=NameNode,name=FSNamesystem`,
+					Attribute: `tag\.Context`,
+					Values:    map[string]float64{"rpc": 1},
+				},
+				// Strings are skipped without Values.
+				{Bean: `Hadoop:service=NameNode,name=FSNamesystem`, Attribute: `tag\.Hostname`},
+			},
+			want: map[string]float64{
+				"namenode_is_active":   1,
+				"namenode_tag_context": 0,
+			},
+		},
+		{
+			name: "composite attributes and booleans",
+			rules: []Rule{{
+				Bean:      `java\.lang:type=Memory`,
+				Attribute: `(Heap|NonHeap)MemoryUsage\.(used|max)|Verbose`,
+			}},
+			want: map[string]float64{
+				"namenode_heap_memory_usage_used":     536870912,
+				"namenode_heap_memory_usage_max":      4294967296,
+				"namenode_non_heap_memory_usage_used": 95420416,
+				"namenode_non_heap_memory_usage_max":  -1,
+				"namenode_verbose":                    0,
+			},
+		},
+		{
+			name: "32-bit counters",
+			rules: []Rule{
+				{
+					Bean:      `Hadoop:service=ResourceManager,name=QueueMetrics,q0=root`,
+					Attribute: `(AppsSubmitted|AppsCompleted)`,
+					Name:      "${1}_total",
+					Type:      Counter,
+					Int32:     true,
+				},
+			},
+			want: map[string]float64{
+				"namenode_apps_submitted_total": 2147483650,
+				"namenode_apps_completed_total": 2147483000,
+			},
+		},
+		{
+			// Only rules marked Int32 undo the wrap-around.
+			name: "other negative values",
+			rules: []Rule{{
+				Bean:      `Hadoop:service=ResourceManager,name=QueueMetrics,q0=root`,
+				Attribute: `AppsSubmitted`,
+				Type:      Counter,
+			}},
+			want: map[string]float64{
+				"namenode_apps_submitted": -2147483646,
+			},
+		},
+		{
+			name: "scale",
+			rules: []Rule{{
+				Bean:      `Hadoop:service=NameNode,name=RpcActivityForPort8020`,
+				Attribute: `(RpcQueueTime)AvgTime`,
+				Name:      "${1}_avg_seconds",
+				Scale:     0.001,
+			}},
+			want: map[string]float64{
+				"namenode_rpc_queue_time_avg_seconds": 0.00025,
+			},
+		},
+		{
+			name:  "default RPC server rules",
+			rules: Default("namenode"),
+			bean:  `Hadoop:service=NameNode,name=RpcActivityForPort\d+`,
+			want: map[string]float64{
+				`namenode_received_bytes_total{port="8020"}`:              1048576,
+				`namenode_received_bytes_total{port="8021"}`:              4096,
+				`namenode_sent_bytes_total{port="8020"}`:                  2097152,
+				`namenode_sent_bytes_total{port="8021"}`:                  8192,
+				`namenode_rpc_queue_time_num_ops_total{port="8020"}`:      500,
+				`namenode_rpc_queue_time_num_ops_total{port="8021"}`:      20,
+				`namenode_rpc_processing_time_num_ops_total{port="8020"}`: 500,
+				`namenode_rpc_processing_time_num_ops_total{port="8021"}`: 20,
+				`namenode_rpc_authentication_failures_total{port="8020"}`: 0,
+				`namenode_rpc_authentication_failures_total{port="8021"}`: 0,
+				`namenode_rpc_slow_calls_total{port="8020"}`:              3,
+				`namenode_rpc_queue_time_avg_seconds{port="8020"}`:        0.00025,
+				`namenode_rpc_queue_time_avg_seconds{port="8021"}`:        0.0005,
+				`namenode_rpc_processing_time_avg_seconds{port="8020"}`:   0.0015,
+				`namenode_rpc_processing_time_avg_seconds{port="8021"}`:   0.002,
+				`namenode_call_queue_length{port="8020"}`:                 0,
+				`namenode_call_queue_length{port="8021"}`:                 1,
+				`namenode_num_open_connections{port="8020"}`:              12,
+				`namenode_num_open_connections{port="8021"}`:              2,
+				// Only the client port has percentiles configured.
+				`namenode_rpc_queue_time_percentile_latency_seconds{interval="60s",percentile="50",port="8020"}`:       0.0002,
+				`namenode_rpc_queue_time_percentile_latency_seconds{interval="60s",percentile="99",port="8020"}`:       0.004,
+				`namenode_rpc_queue_time_percentile_latency_seconds{interval="300s",percentile="99",port="8020"}`:      0.006,
+				`namenode_rpc_processing_time_percentile_latency_seconds{interval="60s",percentile="50",port="8020"}`:  0.001,
+				`namenode_rpc_processing_time_percentile_latency_seconds{interval="60s",percentile="99",port="8020"}`:  0.012,
+				`namenode_rpc_processing_time_percentile_latency_seconds{interval="300s",percentile="99",port="8020"}`: 0.015,
+			},
+		},
+		{
+			// FSNamesystem sums the replicated blocks and the erasure
+			// coded block groups, which Hadoop 3 also reports apart. The
+			// attributes ECBlockGroupsState shares with FSNamesystem are
+			// exported once.
+			name:  "default replication rules",
+			rules: Default("namenode"),
+			bean:  `Hadoop:service=NameNode,name=(?:FSNamesystem|ReplicatedBlocksState|ECBlockGroupsState)`,
+			want: map[string]float64{
+				"namenode_missing_blocks":                     0,
+				"namenode_capacity_total_bytes":               214748364800,
+				"namenode_blocks":                             180,
+				"namenode_files":                              200,
+				"namenode_total_sync_count_total":             1520,
+				"namenode_is_active":                          1,
+				"namenode_under_replicated_blocks":            7,
+				"namenode_pending_replication_blocks":         2,
+				"namenode_pending_deletion_blocks":            0,
+				"namenode_scheduled_replication_blocks":       1,
+				"namenode_low_redundancy_blocks":              9,
+				"namenode_pending_reconstruction_blocks":      2,
+				"namenode_low_redundancy_ec_block_groups":     2,
+				"namenode_corrupt_ec_block_groups":            0,
+				"namenode_missing_ec_block_groups":            0,
+				"namenode_low_redundancy_replicated_blocks":   7,
+				"namenode_corrupt_replicated_blocks":          0,
+				"namenode_missing_replicated_blocks":          0,
+				"namenode_missing_replication_one_blocks":     0,
+				"namenode_bytes_in_future_replicated_blocks":  0,
+				"namenode_pending_deletion_replicated_blocks": 0,
+				"namenode_total_replicated_blocks":            170,
+				"namenode_bytes_in_future_ec_block_groups":    0,
+				"namenode_pending_deletion_ec_blocks":         0,
+				"namenode_total_ec_block_groups":              10,
+			},
+		},
+		{
+			// Operations and re-replications are counters. The NumOps of a
+			// percentile interval count the interval's operations only,
+			// and are not exported.
+			name:  "default NameNodeActivity rules",
+			rules: Default("namenode"),
+			bean:  `Hadoop:service=NameNode,name=NameNodeActivity`,
+			want: map[string]float64{
+				"namenode_create_file_ops_total":                        120,
+				"namenode_files_created_total":                          130,
+				"namenode_files_appended_total":                         4,
+				"namenode_get_block_locations_total":                    900,
+				"namenode_files_renamed_total":                          12,
+				"namenode_get_listing_ops_total":                        300,
+				"namenode_delete_file_ops_total":                        10,
+				"namenode_files_deleted_total":                          11,
+				"namenode_file_info_ops_total":                          2000,
+				"namenode_successful_re_replications_total":             25,
+				"namenode_num_times_re_replication_not_scheduled_total": 2,
+				"namenode_timeout_re_replications_total":                1,
+				"namenode_transactions_batched_in_sync_total":           42,
+				"namenode_transactions_num_ops_total":                   800,
+				"namenode_syncs_num_ops_total":                          400,
+				"namenode_block_report_num_ops_total":                   6,
+				"namenode_transactions_avg_seconds":                     0.00005,
+				"namenode_syncs_avg_seconds":                            0.00125,
+				"namenode_block_report_avg_seconds":                     0.015,
+				"namenode_safe_mode_time_seconds":                       30.5,
+				"namenode_fs_image_load_time_seconds":                   2.1,
+				"namenode_block_ops_queued":                             3,
+				"namenode_block_ops_batched":                            250,
+				`namenode_activity_percentile_latency_seconds{interval="60s",op="Syncs",percentile="50"}`: 0.001,
+				`namenode_activity_percentile_latency_seconds{interval="60s",op="Syncs",percentile="99"}`: 0.007,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := collect(t, tc.rules, tc.bean)
+			for k, v := range tc.want {
+				g, ok := got[k]
+				if !ok {
+					t.Errorf("missing %s", k)
+					continue
+				}
+				if g != v {
+					t.Errorf("%s = %v, want %v", k, g, v)
+				}
+			}
+			for k, v := range got {
+				if _, ok := tc.want[k]; !ok {
+					t.Errorf("unexpected %s = %v", k, v)
+				}
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		rule Rule
+		err  string
+	}{
+		{name: "bean", rule: Rule{Bean: "("}, err: "rule 0: bean: "},
+		{name: "attribute", rule: Rule{Attribute: "("}, err: "rule 0: attribute: "},
+		{name: "type", rule: Rule{Type: "summary"}, err: `rule 0: unknown type "summary"`},
+		{name: "label", rule: Rule{Labels: map[string]string{"a-b": "$1"}}, err: `rule 0: invalid label name "a-b"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New("namenode", []Rule{tc.rule})
+			if err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+				t.Errorf("New() = %v, want an error starting with %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"BlocksTotal":                "blocks_total",
+		"isActive":                   "is_active",
+		"LowRedundancyECBlockGroups": "low_redundancy_ec_block_groups",
+		"capacity_UsedNonDFS_bytes":  "capacity_used_non_dfs_bytes",
+		"Rpc_Queue__avg":             "rpc_queue_avg",
+		"GcTimeMillisG1YoungGen":     "gc_time_millis_g1_young_gen",
+		"rpc_queue_time_avg_seconds": "rpc_queue_time_avg_seconds",
+	} {
+		if got := snakeCase(name); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// TestDefaults compiles the built-in rules of every role.
+func TestDefaults(t *testing.T) {
+	for _, role := range []string{"namenode", "datanode", "journalnode", "resourcemanager", "nodemanager", "jobhistoryserver"} {
+		if _, err := New(role, Default(role)); err != nil {
+			t.Errorf("%s: %v", role, err)
+		}
+	}
+}
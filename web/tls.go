@@ -0,0 +1,151 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// TLSConfig configures the TLS listener.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ClientAuth is one of the names in clientAuthTypes. It defaults to
+	// NoClientCert.
+	ClientAuth string `yaml:"client_auth_type"`
+	// ClientCAs is a PEM bundle of the CAs trusted to sign client
+	// certificates.
+	ClientCAs                string        `yaml:"client_ca_file"`
+	CipherSuites             []cipherSuite `yaml:"cipher_suites"`
+	CurvePreferences         []curve       `yaml:"curve_preferences"`
+	MinVersion               tlsVersion    `yaml:"min_version"`
+	MaxVersion               tlsVersion    `yaml:"max_version"`
+	PreferServerCipherSuites bool          `yaml:"prefer_server_cipher_suites"`
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                           tls.NoClientCert,
+	"NoClientCert":               tls.NoClientCert,
+	"RequestClientCert":          tls.RequestClientCert,
+	"RequireAnyClientCert":       tls.RequireAnyClientCert,
+	"VerifyClientCertIfGiven":    tls.VerifyClientCertIfGiven,
+	"RequireAndVerifyClientCert": tls.RequireAndVerifyClientCert,
+}
+
+// empty reports whether TLS is disabled.
+func (c *TLSConfig) empty() bool {
+	return c.CertFile == "" && c.KeyFile == "" && c.ClientAuth == "" && c.ClientCAs == ""
+}
+
+func (c *TLSConfig) validate() error {
+	if c.empty() {
+		return nil
+	}
+	if c.CertFile == "" || c.KeyFile == "" {
+		return errors.New("cert_file and key_file are required")
+	}
+	if _, ok := clientAuthTypes[c.ClientAuth]; !ok {
+		return fmt.Errorf("invalid client_auth_type %q", c.ClientAuth)
+	}
+	if c.ClientCAs != "" && (c.ClientAuth == "" || c.ClientAuth == "NoClientCert") {
+		return fmt.Errorf("client_ca_file is set but client_auth_type is %q", c.ClientAuth)
+	}
+	if c.MaxVersion < c.MinVersion {
+		return fmt.Errorf("max_version must not be lower than min_version")
+	}
+	return nil
+}
+
+// newTLSConfig reads the files of c.
+func (c *TLSConfig) newTLSConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		Certificates:             []tls.Certificate{cert},
+		ClientAuth:               clientAuthTypes[c.ClientAuth],
+		MinVersion:               uint16(c.MinVersion),
+		MaxVersion:               uint16(c.MaxVersion),
+		PreferServerCipherSuites: c.PreferServerCipherSuites,
+	}
+	for _, s := range c.CipherSuites {
+		cfg.CipherSuites = append(cfg.CipherSuites, uint16(s))
+	}
+	for _, p := range c.CurvePreferences {
+		cfg.CurvePreferences = append(cfg.CurvePreferences, tls.CurveID(p))
+	}
+	if c.ClientCAs != "" {
+		data, err := ioutil.ReadFile(c.ClientCAs)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = x509.NewCertPool()
+		if !cfg.ClientCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificate found in %s", c.ClientCAs)
+		}
+	}
+	return cfg, nil
+}
+
+type tlsVersion uint16
+
+var tlsVersions = map[string]tlsVersion{
+	"TLS13": tls.VersionTLS13,
+	"TLS12": tls.VersionTLS12,
+	"TLS11": tls.VersionTLS11,
+	"TLS10": tls.VersionTLS10,
+}
+
+func (v *tlsVersion) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	version, ok := tlsVersions[s]
+	if !ok {
+		return fmt.Errorf("unknown TLS version %q", s)
+	}
+	*v = version
+	return nil
+}
+
+type cipherSuite uint16
+
+func (c *cipherSuite) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name == s {
+			*c = cipherSuite(suite.ID)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown or insecure cipher suite %q", s)
+}
+
+type curve tls.CurveID
+
+var curves = map[string]curve{
+	"CurveP256": curve(tls.CurveP256),
+	"CurveP384": curve(tls.CurveP384),
+	"CurveP521": curve(tls.CurveP521),
+	"X25519":    curve(tls.X25519),
+}
+
+func (c *curve) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	id, ok := curves[s]
+	if !ok {
+		return fmt.Errorf("unknown curve %q", s)
+	}
+	*c = id
+	return nil
+}
@@ -0,0 +1,194 @@
+// Package web serves the exporter's HTTP endpoints with the TLS and basic
+// authentication settings of a web configuration file, in the format of the
+// Prometheus exporter-toolkit:
+//
+//	tls_server_config:
+//	  cert_file: server.crt
+//	  key_file: server.key
+//	  client_auth_type: RequireAndVerifyClientCert
+//	  client_ca_file: ca.crt
+//	http_server_config:
+//	  http2: true
+//	  headers:
+//	    Strict-Transport-Security: max-age=31536000
+//	basic_auth_users:
+//	  prometheus: $2y$10$...   # bcrypt hash
+//
+// The file is read again for every TLS handshake and request, so
+// certificates and users can be changed without a restart.
+package web
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/log"
+	"golang.org/x/crypto/bcrypt"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Config is the format of the web configuration file.
+type Config struct {
+	TLSConfig  TLSConfig         `yaml:"tls_server_config"`
+	HTTPConfig HTTPConfig        `yaml:"http_server_config"`
+	Users      map[string]string `yaml:"basic_auth_users"`
+}
+
+// HTTPConfig configures the HTTP server.
+type HTTPConfig struct {
+	// HTTP2 enables HTTP/2 over TLS. It defaults to true.
+	HTTP2 bool `yaml:"http2"`
+	// Headers are added to every response.
+	Headers map[string]string `yaml:"headers"`
+}
+
+// getConfig reads and validates the web configuration file at path.
+func getConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{
+		TLSConfig: TLSConfig{
+			MinVersion:               tls.VersionTLS12,
+			MaxVersion:               tls.VersionTLS13,
+			PreferServerCipherSuites: true,
+		},
+		HTTPConfig: HTTPConfig{HTTP2: true},
+	}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := c.TLSConfig.validate(); err != nil {
+		return nil, fmt.Errorf("%s: tls_server_config: %v", path, err)
+	}
+	for user, hash := range c.Users {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("%s: basic_auth_users: %s: %v", path, user, err)
+		}
+	}
+	return c, nil
+}
+
+// ListenAndServe serves server.Handler, or http.DefaultServeMux if it is nil,
+// on server.Addr with the settings of the web configuration file at
+// configPath. Without a configuration file it serves plain HTTP like
+// server.ListenAndServe.
+func ListenAndServe(server *http.Server, configPath string) error {
+	addr := server.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return Serve(ln, server, configPath)
+}
+
+// Serve is like ListenAndServe, accepting connections on ln. ln is closed
+// when Serve returns.
+func Serve(ln net.Listener, server *http.Server, configPath string) error {
+	if configPath == "" {
+		return server.Serve(ln)
+	}
+	c, err := getConfig(configPath)
+	if err != nil {
+		ln.Close()
+		return err
+	}
+	handler := server.Handler
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+	server.Handler = &authHandler{configPath: configPath, handler: handler}
+
+	if c.TLSConfig.empty() {
+		log.Info("TLS is disabled")
+		return server.Serve(ln)
+	}
+	// Fail at startup rather than on the first handshake.
+	if _, err := c.TLSConfig.newTLSConfig(); err != nil {
+		ln.Close()
+		return fmt.Errorf("%s: tls_server_config: %v", configPath, err)
+	}
+	server.TLSConfig = &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c, err := getConfig(configPath)
+			if err != nil {
+				log.Errorf("Error reading web configuration: %v", err)
+				return nil, err
+			}
+			tlsConfig, err := c.TLSConfig.newTLSConfig()
+			if err != nil {
+				log.Errorf("Error loading TLS configuration: %v", err)
+				return nil, err
+			}
+			return tlsConfig, nil
+		},
+	}
+	if !c.HTTPConfig.HTTP2 {
+		server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+	log.Info("TLS is enabled")
+	return server.ServeTLS(ln, "", "")
+}
+
+// authHandler adds the configured headers and requires basic authentication
+// if users are configured.
+type authHandler struct {
+	configPath string
+	handler    http.Handler
+
+	// cache holds the credentials that matched a bcrypt hash, so that
+	// every scrape does not pay for a bcrypt comparison.
+	cache sync.Map
+}
+
+// dummyHash is compared against for unknown users, so that they take as
+// long to reject as wrong passwords.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy"), bcrypt.DefaultCost)
+
+func (h *authHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c, err := getConfig(h.configPath)
+	if err != nil {
+		log.Errorf("Error reading web configuration: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	for name, value := range c.HTTPConfig.Headers {
+		w.Header().Set(name, value)
+	}
+	if len(c.Users) == 0 {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	if user, password, ok := r.BasicAuth(); ok && h.authenticate(c.Users, user, password) {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	w.Header().Set("WWW-Authenticate", "Basic")
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+func (h *authHandler) authenticate(users map[string]string, user, password string) bool {
+	hash, ok := users[user]
+	if !ok {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	key := sha256.Sum256([]byte(hash + "\x00" + user + "\x00" + password))
+	if _, ok := h.cache.Load(key); ok {
+		return true
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return false
+	}
+	h.cache.Store(key, true)
+	return true
+}
@@ -0,0 +1,270 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// tempDir returns a temporary directory removed when the test ends.
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func writeFile(t *testing.T, path, content string) {
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func hash(t *testing.T, password string) string {
+	h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(h)
+}
+
+func TestGetConfig(t *testing.T) {
+	dir := tempDir(t)
+	for _, tc := range []struct {
+		name string
+		yml  string
+		err  string
+	}{
+		{name: "empty", yml: ""},
+		{name: "unknown field", yml: "tls_config: {}\n", err: "field tls_config not found"},
+		{name: "key without certificate", yml: "tls_server_config: {key_file: k}\n", err: "tls_server_config: cert_file and key_file are required"},
+		{name: "client auth type", yml: "tls_server_config: {cert_file: c, key_file: k, client_auth_type: Always}\n", err: `tls_server_config: invalid client_auth_type "Always"`},
+		{name: "client CA without client auth", yml: "tls_server_config: {cert_file: c, key_file: k, client_ca_file: ca}\n", err: `tls_server_config: client_ca_file is set but client_auth_type is ""`},
+		{name: "versions", yml: "tls_server_config: {cert_file: c, key_file: k, min_version: TLS13, max_version: TLS12}\n", err: "tls_server_config: max_version must not be lower than min_version"},
+		{name: "unknown version", yml: "tls_server_config: {min_version: SSL3}\n", err: `unknown TLS version "SSL3"`},
+		{name: "password not hashed", yml: "basic_auth_users: {alice: secret}\n", err: "basic_auth_users: alice: "},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, "web.yml")
+			writeFile(t, path, tc.yml)
+			_, err := getConfig(path)
+			if tc.err == "" {
+				if err != nil {
+					t.Errorf("getConfig() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("getConfig() = %v, want an error containing %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	path := filepath.Join(tempDir(t), "web.yml")
+	writeFile(t, path, "basic_auth_users:\n  alice: "+hash(t, "secret")+"\nhttp_server_config:\n  headers:\n    X-Frame-Options: deny\n")
+	srv := httptest.NewServer(&authHandler{configPath: path, handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("metrics"))
+	})})
+	defer srv.Close()
+
+	check := func(t *testing.T, user, password string, want int) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", srv.URL, nil)
+		if user != "" {
+			req.SetBasicAuth(user, password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s:%s: status %d, want %d", user, password, resp.StatusCode, want)
+		}
+		if want == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") != "Basic" {
+			t.Errorf("WWW-Authenticate = %q", resp.Header.Get("WWW-Authenticate"))
+		}
+		if want != http.StatusInternalServerError && resp.Header.Get("X-Frame-Options") != "deny" {
+			t.Errorf("X-Frame-Options = %q", resp.Header.Get("X-Frame-Options"))
+		}
+	}
+	check(t, "alice", "secret", http.StatusOK)
+	// Again, from the cache.
+	check(t, "alice", "secret", http.StatusOK)
+	check(t, "alice", "wrong", http.StatusUnauthorized)
+	check(t, "bob", "secret", http.StatusUnauthorized)
+	check(t, "", "", http.StatusUnauthorized)
+
+	// The file is read again for every request.
+	writeFile(t, path, "basic_auth_users:\n  bob: "+hash(t, "other")+"\nhttp_server_config:\n  headers:\n    X-Frame-Options: deny\n")
+	check(t, "alice", "secret", http.StatusUnauthorized)
+	check(t, "bob", "other", http.StatusOK)
+	writeFile(t, path, "basic_auth_users: [\n")
+	check(t, "bob", "other", http.StatusInternalServerError)
+}
+
+// pki holds a CA and the files of a server and a client certificate it
+// signed.
+type pki struct {
+	caFile, serverCert, serverKey, clientCert, clientKey string
+	pool                                                 *x509.CertPool
+	client                                               tls.Certificate
+}
+
+func newPKI(t *testing.T, dir string) *pki {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+	p := &pki{caFile: filepath.Join(dir, "ca.crt"), pool: x509.NewCertPool()}
+	p.pool.AddCert(ca)
+	writeFile(t, p.caFile, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})))
+
+	issue := func(serial int64, name string, usage x509.ExtKeyUsage) (certFile, keyFile string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		certFile, keyFile = filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+		writeFile(t, certFile, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+		writeFile(t, keyFile, string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})))
+		return certFile, keyFile
+	}
+	p.serverCert, p.serverKey = issue(2, "server", x509.ExtKeyUsageServerAuth)
+	p.clientCert, p.clientKey = issue(3, "client", x509.ExtKeyUsageClientAuth)
+	if p.client, err = tls.LoadX509KeyPair(p.clientCert, p.clientKey); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestServeTLS(t *testing.T) {
+	dir := tempDir(t)
+	p := newPKI(t, dir)
+	path := filepath.Join(dir, "web.yml")
+	config := func(clientAuth string) string {
+		return "tls_server_config:\n" +
+			"  cert_file: " + p.serverCert + "\n" +
+			"  key_file: " + p.serverKey + "\n" +
+			"  client_auth_type: " + clientAuth + "\n" +
+			"  client_ca_file: " + p.caFile + "\n"
+	}
+	writeFile(t, path, config("RequireAndVerifyClientCert"))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("metrics"))
+	})}
+	done := make(chan error, 1)
+	go func() { done <- Serve(ln, server, path) }()
+	defer func() {
+		server.Close()
+		if err := <-done; err != http.ErrServerClosed {
+			t.Errorf("Serve() = %v", err)
+		}
+	}()
+
+	// get requests the server on a new connection, presenting the client
+	// certificate if withCert is set.
+	get := func(withCert bool) error {
+		tlsConfig := &tls.Config{RootCAs: p.pool}
+		if withCert {
+			tlsConfig.Certificates = []tls.Certificate{p.client}
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		resp, err := client.Get("https://" + ln.Addr().String() + "/metrics")
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		if string(body) != "metrics" {
+			t.Errorf("body = %q", body)
+		}
+		return nil
+	}
+	if err := get(true); err != nil {
+		t.Errorf("with a client certificate: %v", err)
+	}
+	if err := get(false); err == nil {
+		t.Error("accepted a client without certificate")
+	}
+
+	// The file is read again for every handshake.
+	writeFile(t, path, config("VerifyClientCertIfGiven"))
+	if err := get(false); err != nil {
+		t.Errorf("after the client certificate was made optional: %v", err)
+	}
+}
+
+func TestServeErrors(t *testing.T) {
+	dir := tempDir(t)
+	path := filepath.Join(dir, "web.yml")
+	// The files are missing.
+	writeFile(t, path, "tls_server_config: {cert_file: "+filepath.Join(dir, "missing.crt")+", key_file: "+filepath.Join(dir, "missing.key")+"}\n")
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Serve(ln, &http.Server{}, path); err == nil || !strings.Contains(err.Error(), "tls_server_config: ") {
+		t.Errorf("Serve() = %v, want a tls_server_config error", err)
+	}
+	// The listener is closed.
+	if _, err := ln.Accept(); err == nil {
+		t.Error("the listener is still open")
+	}
+}